@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"lab/internal/config"
+	"lab/internal/migrations"
+	"lab/internal/repository/conformance"
+	"log/slog"
+	"os"
+)
+
+// Подкоманда conformance: прогоняет общий набор проверок хранилища лабораторий
+// на настроенной базе. Схема доводится до последней версии перед проверками.
+func runConformance(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
+	db, err := config.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+	migrator, err := migrations.NewMigrator(sqlDB, db.Dialector.Name(), logger)
+	if err != nil {
+		return err
+	}
+	if err := migrator.Up(ctx); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range conformance.Run(ctx, newLabRepository(cfg, db, logger)) {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stdout, "FAIL  %s: %v\n", result.Name, result.Err)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "ok    %s\n", result.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d conformance checks failed on %s", failed, len(conformance.Cases), cfg.DBDriver)
+	}
+	return nil
+}
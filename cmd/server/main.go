@@ -1,41 +1,266 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"gorm.io/gorm"
 	"lab/internal/config"
+	"lab/internal/events"
 	"lab/internal/handlers"
+	"lab/internal/interfaces"
+	"lab/internal/locks"
+	"lab/internal/logging"
+	"lab/internal/metrics"
+	"lab/internal/middleware"
+	"lab/internal/migrations"
 	"lab/internal/repository"
 	"lab/internal/routes"
 	"lab/internal/service"
-	"log"
+	"lab/internal/tracing"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
-	cfg := config.LoadConfig()
+	command, flags := splitCommand(os.Args[1:])
+	cfg, cmdLine, err := config.LoadConfig(flags)
+	if cmdLine.PrintConfig {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			fmt.Fprintln(os.Stderr, printErr)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if cmdLine.PrintConfig {
+		return
+	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+	// Секреты лабораторий регистрируются в redactor и вырезаются из всех записей лога
+	redactor := logging.NewRedactor(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		AddSource: true,
 	}))
+	logger := slog.New(redactor)
+
+	if len(command) > 0 {
+		switch command[0] {
+		case "migrate":
+			err = runMigrate(context.Background(), command[1:], cfg, logger)
+		case "conformance":
+			err = runConformance(context.Background(), cfg, logger)
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", command[0])
+			os.Exit(2)
+		}
+		if err != nil {
+			logger.Error("Command failed", "command", command[0], "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		File:        cfg.TracingFile,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Error("Error initializing tracing", "error", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
 
 	db, err := config.InitDB(cfg)
 	if err != nil {
 		logger.Error("Error initializing database", "error", err)
 		return
 	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("Error getting database pool", "error", err)
+		return
+	}
+	migrator, err := migrations.NewMigrator(sqlDB, db.Dialector.Name(), logger)
+	if err != nil {
+		logger.Error("Error loading migrations", "error", err)
+		return
+	}
+	if cfg.DBAutoMigrate {
+		if err := migrator.Up(context.Background()); err != nil {
+			logger.Error("Error migrating database", "error", err)
+			return
+		}
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		logger.Error("Database schema check failed", "error", err)
+		return
+	}
 
-	labRepository := repository.NewLabRepository(db, logger)
+	labRepository := newLabRepository(cfg, db, logger)
+	labCheckRepository := repository.NewLabCheckRepository(db, logger)
+	labTemplateRepository := repository.NewLabTemplateRepository(db, logger)
+	labSecretRepository := repository.NewLabSecretRepository(db, logger)
+	flagSubmissionRepository := repository.NewFlagSubmissionRepository(db, logger)
+	recordingRepository := repository.NewRecordingRepository(db, logger)
+	execAuditRepository := repository.NewExecAuditRepository(db, logger)
+	webhookRepository := repository.NewWebhookRepository(db, logger)
+	idempotencyRepository := repository.NewIdempotencyRepository(db, logger)
+	warmPoolRepository := repository.NewWarmPoolRepository(db, logger)
 
-	labService := service.NewLabService(labRepository, cfg.TaskServiceURL, logger)
+	secretService := service.NewSecretService(labSecretRepository, cfg.SecretsKey, cfg.FlagSecret, redactor, logger)
+	eventBus := events.NewBus(cfg.EventHistorySize)
+	portAllocator := service.NewPortAllocator(cfg.PortRangeStart, cfg.PortRangeEnd)
+	auditService := service.NewAuditService(execAuditRepository, redactor, logger)
+	// Блокировки операций над лабораториями; при нескольких репликах — общие через Postgres
+	labLocker := locks.NewLocal()
+	if cfg.LockBackend == locks.BackendPostgres {
+		labLocker = locks.NewPostgres(sqlDB, logger)
+	}
+	labLimits := service.LabLimits{
+		DefaultCPU:     cfg.DefaultCPULimit,
+		DefaultMemory:  cfg.DefaultMemoryLimit,
+		DefaultPids:    cfg.DefaultPidsLimit,
+		MaxLabsPerUser: cfg.MaxLabsPerUser,
+		DefaultTTL:     time.Duration(cfg.DefaultTTLMinutes) * time.Minute,
+		MaxTTL:         time.Duration(cfg.MaxTTLMinutes) * time.Minute,
+		TrashRetention: time.Duration(cfg.TrashRetentionHours) * time.Hour,
+	}
+	labService := service.NewLabService(labRepository, labTemplateRepository, secretService, auditService, eventBus, portAllocator,
+		labLocker, time.Duration(cfg.LockWaitSeconds)*time.Second, cfg.RuntimeBinary, labLimits, cfg.TaskServiceURL, logger)
+	warmPoolService := service.NewWarmPoolService(labService, warmPoolRepository, logger)
+	templateService := service.NewTemplateService(labTemplateRepository, logger)
+	checkService := service.NewCheckService(labService, labCheckRepository, logger)
+	flagService := service.NewFlagService(labService, flagSubmissionRepository, cfg.FlagRateLimit, logger)
+	fileService := service.NewFileService(labService, cfg.FileAllowedRoots, cfg.FileMaxUploadBytes, cfg.FileMaxDownloadBytes, cfg.FilePreviewMaxBytes, logger)
+	webhookService := service.NewWebhookService(webhookRepository, eventBus, cfg.SecretsKey,
+		&http.Client{Timeout: time.Duration(cfg.WebhookTimeoutSeconds) * time.Second},
+		cfg.WebhookMaxAttempts, time.Duration(cfg.WebhookBackoffSeconds)*time.Second,
+		time.Duration(cfg.WebhookMaxBackoff)*time.Second, logger)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepository, time.Duration(cfg.IdempotencyTTLHours)*time.Hour, logger)
+	terminalService := service.NewTerminalService(labService, recordingRepository, cfg.RecordingsDir, logger)
+
+	healthService := service.NewHealthService([]service.HealthCheck{
+		service.DatabaseCheck(sqlDB.PingContext),
+		labService.RuntimeCheck(),
+		labService.TaskServiceCheck(cfg.HealthTaskServiceCritical),
+		service.PortCapacityCheck(portAllocator, cfg.HealthMinFreePorts),
+	}, time.Duration(cfg.HealthTimeoutSeconds)*time.Second, logger)
 
 	labHandler := handlers.NewLabHandler(labService, cfg.TaskServiceURL, logger)
+	checkHandler := handlers.NewCheckHandler(checkService, logger)
+	templateHandler := handlers.NewTemplateHandler(templateService, logger)
+	flagHandler := handlers.NewFlagHandler(flagService, logger)
+	fileHandler := handlers.NewFileHandler(fileService, logger)
+	connections := handlers.NewConnections()
+	terminalHandler := handlers.NewTerminalHandler(terminalService, connections, cfg.WebSocketAllowedOrigins, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, labService, logger)
+	eventHandler := handlers.NewEventHandler(eventBus, connections, cfg.WebSocketAllowedOrigins, logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, logger)
+	healthHandler := handlers.NewHealthHandler(healthService)
+	warmPoolHandler := handlers.NewWarmPoolHandler(warmPoolService, logger)
+
+	if err := labService.ReservePorts(context.Background()); err != nil {
+		logger.Error("Error reserving ports of existing labs", "error", err)
+		return
+	}
+	if err := labService.RegisterSecrets(context.Background()); err != nil {
+		logger.Error("Error registering secrets of existing labs", "error", err)
+		return
+	}
+	metrics.RegisterPortUsage(portAllocator.Usage)
+	metrics.RegisterLabCounts(labRepository.CountLabs)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(5)
+	// Остановка лабораторий с истёкшим сроком жизни
+	go func() {
+		defer workers.Done()
+		labService.RunExpiryWorker(workersCtx, time.Duration(cfg.ExpiryInterval)*time.Second)
+	}()
+	// Окончательное удаление лабораторий из корзины
+	go func() {
+		defer workers.Done()
+		labService.RunPurgeWorker(workersCtx, time.Duration(cfg.TrashPurgeInterval)*time.Second)
+	}()
+	// Доставка событий подписчикам вебхуков
+	go func() {
+		defer workers.Done()
+		webhookService.Run(workersCtx)
+	}()
+	// Поддержание тёплых пулов лабораторий по расписанию
+	go func() {
+		defer workers.Done()
+		warmPoolService.Run(workersCtx, time.Duration(cfg.WarmPoolInterval)*time.Second)
+	}()
+	// Удаление устаревших ключей идемпотентности
+	go func() {
+		defer workers.Done()
+		idempotencyService.RunCleanupWorker(workersCtx, time.Duration(cfg.IdempotencyCleanupInterval)*time.Second)
+	}()
 
 	router := gin.Default()
-	routes.SetupRoutes(router, labHandler)
+	router.Use(otelgin.Middleware(tracing.ServiceName))
+	router.Use(middleware.Metrics())
+	router.Use(middleware.Auth(cfg.JWTSecret, logger))
+	routes.SetupRoutes(router, labHandler, checkHandler, templateHandler, flagHandler, fileHandler, terminalHandler, auditHandler, eventHandler, webhookHandler, healthHandler, warmPoolHandler,
+		middleware.Idempotency(idempotencyService, logger))
+
+	server := &http.Server{
+		Addr:    cfg.ServerPort,
+		Handler: router,
+	}
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server running", "addr", cfg.ServerPort)
+		serverErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
+		logger.Error("Server failed", "error", err)
+	case <-signalCtx.Done():
+		logger.Info("Shutting down", "timeout", shutdownTimeout)
+	}
+	stopSignals()
+
+	// Порядок остановки: перестаём принимать запросы и закрываем WebSocket/SSE,
+	// дожидаемся запросов и операций над лабораториями, затем фоновых задач и только после этого закрываем БД
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	server.RegisterOnShutdown(func() { connections.CloseAll(ctx) })
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("HTTP server did not shut down cleanly", "error", err)
+	}
+	if err := labService.Drain(ctx); err != nil {
+		logger.Error("Lab operations did not finish before shutdown deadline", "error", err)
+	}
+	stopWorkers()
+	workers.Wait()
+	webhookService.Wait()
+	if err := sqlDB.Close(); err != nil {
+		logger.Error("Error closing database", "error", err)
+	}
+	logger.Info("Server stopped")
+}
 
-	log.Printf("Server running on port %s", cfg.ServerPort)
-	if err := router.Run(cfg.ServerPort); err != nil {
-		log.Fatal(err)
+// Хранилище лабораторий выбирается по драйверу базы; остальные хранилища
+// используют только переносимые запросы gorm и общие для обоих драйверов
+func newLabRepository(cfg config.Config, db *gorm.DB, logger *slog.Logger) interfaces.LabInterface {
+	if cfg.DBDriver == config.DriverSQLite {
+		return repository.NewSQLiteLabRepository(db, logger)
 	}
+	return repository.NewLabRepository(db, logger)
 }
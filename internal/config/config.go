@@ -5,55 +5,167 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"lab/internal/model"
-	"os"
+	"lab/internal/locks"
+	"lab/internal/tracing"
 )
 
+// Config — настройки сервиса. Тег key задаёт ключ в файле конфигурации и имя флага,
+// env — переменную окружения, secret — значение, которое скрывается в --print-config.
 type Config struct {
-	DBUser         string
-	DBPassword     string
-	DBName         string
-	DBHost         string
-	DBPort         string
-	JWTSecret      string
-	TaskServiceURL string
-	ServerPort     string
+	ServerPort string `key:"server.addr" env:"SERVER_PORT"`
+
+	DBDriver string `key:"db.driver" env:"DB_DRIVER"` // postgres или sqlite для локальной разработки
+	DBPath   string `key:"db.path" env:"DB_PATH"`     // Файл базы для драйвера sqlite
+
+	DBUser     string `key:"db.user" env:"DB_USER"`
+	DBPassword string `key:"db.password" env:"DB_PASSWORD" secret:"true"`
+	DBName     string `key:"db.name" env:"DB_NAME"`
+	DBHost     string `key:"db.host" env:"DB_HOST"`
+	DBPort     string `key:"db.port" env:"DB_PORT"`
+	// Применять миграции при запуске; без этого сервис только проверяет версию схемы
+	DBAutoMigrate bool `key:"db.auto_migrate" env:"DB_AUTO_MIGRATE"`
+
+	JWTSecret      string `key:"auth.jwt_secret" env:"JWT_SECRET" secret:"true"`
+	SecretsKey     string `key:"secrets.key" env:"SECRETS_KEY" secret:"true"`  // Ключ шифрования секретов лабораторий
+	FlagSecret     string `key:"flags.secret" env:"FLAG_SECRET" secret:"true"` // Ключ HMAC для флагов CTF
+	FlagRateLimit  int    `key:"flags.rate_limit" env:"FLAG_RATE_LIMIT"`       // Попыток сдачи флага в минуту на лабораторию и пользователя
+	TaskServiceURL string `key:"task_service.url" env:"TASK_SERVICE_URL"`
+
+	RuntimeBinary  string `key:"runtime.binary" env:"RUNTIME_BINARY"`      // CLI среды контейнеров, совместимый с docker
+	PortRangeStart int    `key:"ports.range_start" env:"PORT_RANGE_START"` // Диапазон портов хоста для терминалов лабораторий
+	PortRangeEnd   int    `key:"ports.range_end" env:"PORT_RANGE_END"`
+
+	// Ограничения контейнера, если задание не задаёт свои
+	DefaultCPULimit    string `key:"limits.default_cpu" env:"LAB_DEFAULT_CPU"`
+	DefaultMemoryLimit string `key:"limits.default_memory" env:"LAB_DEFAULT_MEMORY"`
+	DefaultPidsLimit   int    `key:"limits.default_pids" env:"LAB_DEFAULT_PIDS"`
+	MaxLabsPerUser     int    `key:"quotas.max_labs_per_user" env:"LAB_MAX_PER_USER"` // 0 — без ограничения
+
+	DefaultTTLMinutes int `key:"ttl.default_minutes" env:"LAB_DEFAULT_TTL_MINUTES"` // Для заданий без ttl_minutes, 0 — бессрочно
+	MaxTTLMinutes     int `key:"ttl.max_minutes" env:"LAB_MAX_TTL_MINUTES"`         // Верхняя граница срока жизни, 0 — без ограничения
+	ExpiryInterval    int `key:"ttl.expiry_interval" env:"EXPIRY_INTERVAL"`         // Период проверки истёкших лабораторий, в секундах
+
+	// Блокировка операций над лабораторией: local — внутри процесса, postgres — общая для реплик
+	LockBackend     string `key:"locks.backend" env:"LAB_LOCK_BACKEND"`
+	LockWaitSeconds int    `key:"locks.wait_seconds" env:"LAB_LOCK_WAIT_SECONDS"` // Сколько ждать занятую лабораторию до ответа 409
+
+	TrashRetentionHours int `key:"trash.retention_hours" env:"TRASH_RETENTION_HOURS"` // Сколько удалённая лаборатория доступна для восстановления
+	TrashPurgeInterval  int `key:"trash.purge_interval" env:"TRASH_PURGE_INTERVAL"`   // Период окончательного удаления из корзины, в секундах
+
+	WarmPoolInterval int `key:"pools.interval" env:"WARM_POOL_INTERVAL"` // Период проверки тёплых пулов и их расписаний, в секундах
+
+	IdempotencyTTLHours        int `key:"idempotency.ttl_hours" env:"IDEMPOTENCY_TTL_HOURS"`               // Сколько хранится ответ на запрос с Idempotency-Key
+	IdempotencyCleanupInterval int `key:"idempotency.cleanup_interval" env:"IDEMPOTENCY_CLEANUP_INTERVAL"` // Период удаления устаревших ключей, в секундах
+
+	FileAllowedRoots     []string `key:"files.allowed_roots" env:"FILE_ALLOWED_ROOTS"` // Каталоги контейнера, доступные для загрузки и скачивания файлов
+	FileMaxUploadBytes   int64    `key:"files.max_upload_bytes" env:"FILE_MAX_UPLOAD_BYTES"`
+	FileMaxDownloadBytes int64    `key:"files.max_download_bytes" env:"FILE_MAX_DOWNLOAD_BYTES"`
+	FilePreviewMaxBytes  int64    `key:"files.preview_max_bytes" env:"FILE_PREVIEW_MAX_BYTES"`
+	RecordingsDir        string   `key:"recordings.dir" env:"RECORDINGS_DIR"` // Каталог для записей терминальных сессий
+	// Origin фронтендов, которым разрешены websocket терминала и событий; свой хост разрешён всегда
+	WebSocketAllowedOrigins []string `key:"websocket.allowed_origins" env:"WEBSOCKET_ALLOWED_ORIGINS"`
+	EventHistorySize        int      `key:"events.history_size" env:"EVENT_HISTORY_SIZE"` // Сколько последних событий хранится для переподключившихся клиентов
+
+	WebhookMaxAttempts    int `key:"webhooks.max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`               // Попыток доставки вебхука до признания её неудачной
+	WebhookBackoffSeconds int `key:"webhooks.backoff_seconds" env:"WEBHOOK_BACKOFF_SECONDS"`         // Задержка перед повтором, удваивается с каждой попыткой
+	WebhookMaxBackoff     int `key:"webhooks.max_backoff_seconds" env:"WEBHOOK_MAX_BACKOFF_SECONDS"` // Максимальная задержка между попытками, в секундах
+	WebhookTimeoutSeconds int `key:"webhooks.timeout_seconds" env:"WEBHOOK_TIMEOUT_SECONDS"`
+
+	ShutdownTimeoutSeconds int `key:"shutdown.timeout_seconds" env:"SHUTDOWN_TIMEOUT_SECONDS"` // Сколько ждать незавершённых операций при остановке
+
+	HealthTimeoutSeconds      int  `key:"health.timeout_seconds" env:"HEALTH_TIMEOUT_SECONDS"`             // Время на каждую проверку готовности
+	HealthTaskServiceCritical bool `key:"health.task_service_critical" env:"HEALTH_TASK_SERVICE_CRITICAL"` // Недоступный сервис заданий делает сервис неготовым
+	HealthMinFreePorts        int  `key:"health.min_free_ports" env:"HEALTH_MIN_FREE_PORTS"`
+
+	TracingExporter    string  `key:"tracing.exporter" env:"TRACING_EXPORTER"`         // none, stdout, file или otlp
+	TracingFile        string  `key:"tracing.file" env:"TRACING_FILE"`                 // Файл для экспортёра file
+	TracingSampleRatio float64 `key:"tracing.sample_ratio" env:"TRACING_SAMPLE_RATIO"` // Доля записываемых трассировок
 }
 
-func LoadConfig() Config {
+// Default возвращает значения по умолчанию. Ключи и пароль базы данных по умолчанию не заданы:
+// без них сервис не запустится.
+func Default() Config {
 	return Config{
-		DBUser:         getEnv("DB_USER", "user"),
-		DBPassword:     getEnv("DB_PASSWORD", "password"),
-		DBName:         getEnv("DB_NAME", "lab_db"),
-		DBHost:         getEnv("DB_HOST", "localhost"),
-		DBPort:         getEnv("DB_PORT", "5434"),
-		JWTSecret:      getEnv("JWT_SECRET", "my_secret_key"),
-		TaskServiceURL: getEnv("TASK_SERVICE_URL", "http://localhost:8086"),
-		ServerPort:     getEnv("SERVER_PORT", ":8082"),
+		ServerPort: ":8082",
+
+		DBDriver: DriverPostgres,
+		DBPath:   "lab.db",
+
+		DBUser: "user",
+		DBName: "lab_db",
+		DBHost: "localhost",
+		DBPort: "5434",
+
+		FlagRateLimit:  10,
+		TaskServiceURL: "http://localhost:8086",
+
+		RuntimeBinary:  "docker",
+		PortRangeStart: 20000,
+		PortRangeEnd:   29999,
+
+		ExpiryInterval: 60,
+
+		LockBackend:     locks.BackendLocal,
+		LockWaitSeconds: 5,
+
+		TrashRetentionHours: 7 * 24,
+		TrashPurgeInterval:  300,
+
+		WarmPoolInterval: 60,
+
+		IdempotencyTTLHours:        24,
+		IdempotencyCleanupInterval: 600,
+
+		FileAllowedRoots:     []string{"/root", "/home", "/tmp", "/workspace"},
+		FileMaxUploadBytes:   10 << 20,
+		FileMaxDownloadBytes: 100 << 20,
+		FilePreviewMaxBytes:  1 << 20,
+		RecordingsDir:        "./recordings",
+		EventHistorySize:     1000,
+
+		WebhookMaxAttempts:    6,
+		WebhookBackoffSeconds: 5,
+		WebhookMaxBackoff:     3600,
+		WebhookTimeoutSeconds: 10,
+
+		ShutdownTimeoutSeconds: 60,
+
+		HealthTimeoutSeconds: 3,
+		HealthMinFreePorts:   1,
+
+		TracingExporter:    tracing.ExporterNone,
+		TracingFile:        "traces.json",
+		TracingSampleRatio: 1,
 	}
 }
 
+// Драйверы базы данных
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 func InitDB(cfg Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+	var dialector gorm.Dialector
+	switch cfg.DBDriver {
+	case DriverSQLite:
+		dialector = openSQLite(cfg.DBPath)
+	default:
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+			cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+		dialector = postgres.Open(dsn)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
-
-	if err = db.AutoMigrate(&model.Lab{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to enable database tracing: %w", err)
 	}
 
+	// Схема создаётся версионированными миграциями из пакета migrations
 	return db, nil
 }
-
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
-}
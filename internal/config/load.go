@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Значение секретного поля в выводе --print-config
+const redacted = "<redacted>"
+
+// CommandLine — параметры запуска, которые не входят в конфигурацию
+type CommandLine struct {
+	ConfigFile  string // --config или CONFIG_FILE, YAML или TOML по расширению
+	PrintConfig bool   // --print-config: вывести итоговую конфигурацию и выйти
+}
+
+// Поле Config, доступное для настройки
+type field struct {
+	key    string
+	env    string
+	secret bool
+	index  int
+}
+
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	list := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		list = append(list, field{
+			key:    f.Tag.Get("key"),
+			env:    f.Tag.Get("env"),
+			secret: f.Tag.Get("secret") == "true",
+			index:  i,
+		})
+	}
+	return list
+}
+
+// LoadConfig собирает конфигурацию из слоёв: значения по умолчанию, файл, переменные окружения, флаги.
+// Каждый следующий слой перекрывает предыдущий. Ошибки разбора и проверки возвращаются все сразу.
+func LoadConfig(args []string) (Config, CommandLine, error) {
+	cfg := Default()
+	var cmdLine CommandLine
+	var errs []error
+
+	// Флаги разбираются первыми, чтобы узнать путь к файлу, а применяются последними
+	type flagValue struct {
+		field field
+		value string
+	}
+	var flagValues []flagValue
+	fs := flag.NewFlagSet("lab", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&cmdLine.ConfigFile, "config", os.Getenv("CONFIG_FILE"), "path to YAML or TOML config file")
+	fs.BoolVar(&cmdLine.PrintConfig, "print-config", false, "print effective config with secrets redacted and exit")
+	for _, f := range fields() {
+		set := func(value string) error {
+			flagValues = append(flagValues, flagValue{f, value})
+			return nil
+		}
+		usage := "env " + f.env
+		if reflect.TypeOf(Config{}).Field(f.index).Type.Kind() == reflect.Bool {
+			fs.BoolFunc(f.key, usage, func(value string) error { return set(value) })
+		} else {
+			fs.Func(f.key, usage, set)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, cmdLine, fmt.Errorf("flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return cfg, cmdLine, fmt.Errorf("flags: unexpected argument %q", fs.Arg(0))
+	}
+
+	if cmdLine.ConfigFile != "" {
+		if err := cfg.loadFile(cmdLine.ConfigFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range fields() {
+		if value, ok := os.LookupEnv(f.env); ok {
+			if err := cfg.set(f, value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+			}
+		}
+	}
+	for _, fv := range flagValues {
+		if err := cfg.set(fv.field, fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("flag --%s: %w", fv.field.key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, cmdLine, errors.Join(errs...)
+	}
+	return cfg, cmdLine, cfg.Validate()
+}
+
+// Читает файл конфигурации. Секции файла соответствуют частям ключа до точки: [db] user = ...
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	values := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	flat := map[string]any{}
+	flatten("", values, flat)
+	byKey := map[string]field{}
+	for _, f := range fields() {
+		byKey[f.key] = f
+	}
+	var errs []error
+	for key, value := range flat {
+		f, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+			continue
+		}
+		if list, ok := value.([]any); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(items, ",")
+		}
+		if err := c.set(f, fmt.Sprint(value)); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func flatten(prefix string, values map[string]any, out map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+// Записывает строковое значение в поле с разбором по его типу
+func (c *Config) set(f field, value string) error {
+	target := reflect.ValueOf(c).Elem().Field(f.index)
+	value = strings.TrimSpace(value)
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		target.SetInt(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		target.SetFloat(parsed)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		target.SetBool(parsed)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		target.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported field type %s", target.Type())
+	}
+	return nil
+}
+
+// Print выводит конфигурацию в формате YAML, значения секретов заменяются на <redacted>
+func (c Config) Print(w io.Writer) error {
+	out := map[string]any{}
+	value := reflect.ValueOf(c)
+	for _, f := range fields() {
+		var v any = value.Field(f.index).Interface()
+		if f.secret && v != "" {
+			v = redacted
+		}
+		section := out
+		parts := strings.Split(f.key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := section[part].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				section[part] = next
+			}
+			section = next
+		}
+		section[parts[len(parts)-1]] = v
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(out); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
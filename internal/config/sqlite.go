@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"net/url"
+	"time"
+)
+
+// Имя драйвера database/sql, под которым зарегистрирована обёртка над SQLite
+const sqliteUTCDriver = "sqlite-utc"
+
+func init() {
+	db, err := sql.Open(sqlite.DriverName, ":memory:")
+	if err != nil {
+		panic(err)
+	}
+	sql.Register(sqliteUTCDriver, utcDriver{db.Driver()})
+	db.Close()
+}
+
+// Открывает файл SQLite с внешними ключами, WAL и ожиданием блокировки вместо ошибки
+// "database is locked". Транзакции сразу берут блокировку на запись, чтобы конкурирующие
+// записи ждали друг друга, а не падали при повышении блокировки.
+func openSQLite(path string) gorm.Dialector {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Set("_txlock", "immediate")
+	return &sqlite.Dialector{DriverName: sqliteUTCDriver, DSN: path + "?" + query.Encode()}
+}
+
+// utcDriver приводит все параметры-времена к UTC. SQLite хранит время строкой
+// и сравнивает его как строку, поэтому значения с разными смещениями пояса
+// сравнивались бы неверно, а Postgres сравнивает моменты времени.
+type utcDriver struct {
+	driver.Driver
+}
+
+func (d utcDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{conn}, nil
+}
+
+// utcConn пробрасывает к соединению SQLite контекстные методы, которые
+// database/sql ищет через приведение типов
+type utcConn struct {
+	driver.Conn
+}
+
+// Сначала выполняет стандартное преобразование database/sql, чтобы *time.Time тоже попали под приведение
+func (c utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := converted.(time.Time); ok {
+		converted = t.UTC()
+	}
+	value.Value = converted
+	return nil
+}
+
+func (c utcConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c utcConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c utcConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c utcConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
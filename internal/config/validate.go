@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"lab/internal/locks"
+	"lab/internal/tracing"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// Минимальная длина ключей подписи и шифрования
+const minSecretLength = 16
+
+// Значения, которые раньше были значениями по умолчанию или встречаются в примерах
+var insecureValues = map[string]bool{
+	"password":       true,
+	"my_secret_key":  true,
+	"my_secrets_key": true,
+	"my_flag_secret": true,
+	"secret":         true,
+	"changeme":       true,
+}
+
+var memoryLimitPattern = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// Validate проверяет все поля и возвращает все найденные ошибки сразу
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(c.ServerPort)
+	check(err == nil, "server.addr", "must be host:port or :port, got %q", c.ServerPort)
+
+	switch c.DBDriver {
+	case DriverPostgres:
+		check(c.DBHost != "", "db.host", "must be set")
+		check(c.DBUser != "", "db.user", "must be set")
+		check(c.DBName != "", "db.name", "must be set")
+		check(validPort(c.DBPort), "db.port", "must be a port number, got %q", c.DBPort)
+		check(c.DBPassword != "", "db.password", "must be set")
+		check(!insecureValues[c.DBPassword], "db.password", "must not be a well-known insecure value")
+	case DriverSQLite:
+		check(c.DBPath != "", "db.path", "must be set for the sqlite driver")
+	default:
+		check(false, "db.driver", "must be one of postgres, sqlite, got %q", c.DBDriver)
+	}
+
+	for _, secret := range []struct {
+		key, value string
+	}{{"auth.jwt_secret", c.JWTSecret}, {"secrets.key", c.SecretsKey}, {"flags.secret", c.FlagSecret}} {
+		switch {
+		case secret.value == "":
+			check(false, secret.key, "must be set")
+		case insecureValues[secret.value]:
+			check(false, secret.key, "must not be a well-known insecure value")
+		default:
+			check(len(secret.value) >= minSecretLength, secret.key, "must be at least %d characters", minSecretLength)
+		}
+	}
+	check(c.FlagRateLimit > 0, "flags.rate_limit", "must be positive")
+
+	taskURL, err := url.Parse(c.TaskServiceURL)
+	check(err == nil && (taskURL.Scheme == "http" || taskURL.Scheme == "https") && taskURL.Host != "",
+		"task_service.url", "must be an http(s) URL, got %q", c.TaskServiceURL)
+
+	check(c.RuntimeBinary != "", "runtime.binary", "must be set")
+	check(c.PortRangeStart >= 1024 && c.PortRangeStart <= 65535, "ports.range_start", "must be between 1024 and 65535")
+	check(c.PortRangeEnd >= c.PortRangeStart && c.PortRangeEnd <= 65535, "ports.range_end", "must be between ports.range_start and 65535")
+
+	if c.DefaultCPULimit != "" {
+		cpus, err := strconv.ParseFloat(c.DefaultCPULimit, 64)
+		check(err == nil && cpus > 0, "limits.default_cpu", "must be a positive number of CPUs, got %q", c.DefaultCPULimit)
+	}
+	check(c.DefaultMemoryLimit == "" || memoryLimitPattern.MatchString(c.DefaultMemoryLimit),
+		"limits.default_memory", "must be a size like 512m or 2g, got %q", c.DefaultMemoryLimit)
+	check(c.DefaultPidsLimit >= 0, "limits.default_pids", "must not be negative")
+	check(c.MaxLabsPerUser >= 0, "quotas.max_labs_per_user", "must not be negative")
+
+	check(c.DefaultTTLMinutes >= 0, "ttl.default_minutes", "must not be negative")
+	check(c.MaxTTLMinutes >= 0, "ttl.max_minutes", "must not be negative")
+	check(c.MaxTTLMinutes == 0 || c.DefaultTTLMinutes <= c.MaxTTLMinutes, "ttl.default_minutes", "must not exceed ttl.max_minutes")
+	check(c.ExpiryInterval > 0, "ttl.expiry_interval", "must be positive")
+	switch c.LockBackend {
+	case locks.BackendLocal:
+	case locks.BackendPostgres:
+		check(c.DBDriver == DriverPostgres, "locks.backend", "postgres locks require db.driver postgres")
+	default:
+		check(false, "locks.backend", "must be one of local, postgres, got %q", c.LockBackend)
+	}
+	check(c.LockWaitSeconds >= 0, "locks.wait_seconds", "must not be negative")
+	check(c.TrashRetentionHours > 0, "trash.retention_hours", "must be positive")
+	check(c.TrashPurgeInterval > 0, "trash.purge_interval", "must be positive")
+	check(c.WarmPoolInterval > 0, "pools.interval", "must be positive")
+	check(c.IdempotencyTTLHours > 0, "idempotency.ttl_hours", "must be positive")
+	check(c.IdempotencyCleanupInterval > 0, "idempotency.cleanup_interval", "must be positive")
+
+	check(len(c.FileAllowedRoots) > 0, "files.allowed_roots", "must list at least one directory")
+	for _, root := range c.FileAllowedRoots {
+		check(path.IsAbs(root), "files.allowed_roots", "%q is not an absolute path", root)
+	}
+	check(c.FileMaxUploadBytes > 0, "files.max_upload_bytes", "must be positive")
+	check(c.FileMaxDownloadBytes > 0, "files.max_download_bytes", "must be positive")
+	check(c.FilePreviewMaxBytes > 0, "files.preview_max_bytes", "must be positive")
+	check(c.RecordingsDir != "", "recordings.dir", "must be set")
+	for _, origin := range c.WebSocketAllowedOrigins {
+		parsed, err := url.Parse(origin)
+		check(err == nil && parsed.Scheme != "" && parsed.Host != "" && parsed.Path == "",
+			"websocket.allowed_origins", "%q is not an origin like https://example.com", origin)
+	}
+	check(c.EventHistorySize > 0, "events.history_size", "must be positive")
+
+	check(c.WebhookMaxAttempts > 0, "webhooks.max_attempts", "must be positive")
+	check(c.WebhookBackoffSeconds > 0, "webhooks.backoff_seconds", "must be positive")
+	check(c.WebhookMaxBackoff >= c.WebhookBackoffSeconds, "webhooks.max_backoff_seconds", "must not be less than webhooks.backoff_seconds")
+	check(c.WebhookTimeoutSeconds > 0, "webhooks.timeout_seconds", "must be positive")
+
+	check(c.ShutdownTimeoutSeconds > 0, "shutdown.timeout_seconds", "must be positive")
+	check(c.HealthTimeoutSeconds > 0, "health.timeout_seconds", "must be positive")
+	check(c.HealthMinFreePorts >= 0, "health.min_free_ports", "must not be negative")
+
+	switch c.TracingExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	case tracing.ExporterFile:
+		check(c.TracingFile != "", "tracing.file", "must be set for the file exporter")
+	default:
+		check(false, "tracing.exporter", "must be one of none, stdout, file, otlp, got %q", c.TracingExporter)
+	}
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+
+	return errors.Join(errs...)
+}
+
+func validPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port > 0 && port <= 65535
+}
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"lab/internal/model"
+	"sync"
+	"time"
+)
+
+// Размер буфера подписчика. Подписчик, который не успевает читать, отключается
+// и должен переподключиться с курсором последнего полученного события.
+const subscriberBuffer = 256
+
+// Filter ограничивает события подписки. Нулевые поля не фильтруют.
+type Filter struct {
+	LabID   uint
+	OwnerID uint
+}
+
+func (f Filter) match(event model.LabEvent) bool {
+	return (f.LabID == 0 || f.LabID == event.LabID) && (f.OwnerID == 0 || f.OwnerID == event.OwnerID)
+}
+
+// Bus — шина событий внутри процесса. Последние события хранятся в кольцевом буфере,
+// чтобы переподключившиеся клиенты получили пропущенное.
+type Bus struct {
+	mu          sync.Mutex
+	history     []model.LabEvent
+	size        int
+	next        int // Позиция для следующей записи в history
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBus(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = 1
+	}
+	return &Bus{
+		history:     make([]model.LabEvent, 0, historySize),
+		size:        historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription — подписка на события. Events закрывается при Close или если подписчик отстал.
+type Subscription struct {
+	Events <-chan model.LabEvent
+	// Пропущенные события уже вытеснены из истории, клиенту нужно перечитать состояние целиком
+	Truncated bool
+
+	events chan model.LabEvent
+	filter Filter
+	bus    *Bus
+}
+
+// Publish присваивает событию ID и время и рассылает его подписчикам
+func (b *Bus) Publish(event model.LabEvent) model.LabEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if len(b.history) < b.size {
+		b.history = append(b.history, event)
+	} else {
+		b.history[b.next] = event
+	}
+	b.next = (b.next + 1) % b.size
+
+	for sub := range b.subscribers {
+		if !sub.filter.match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe подписывает на события после курсора after (0 — только новые события).
+// Пропущенные события из истории сразу помещаются в канал подписки.
+func (b *Bus) Subscribe(filter Filter, after uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{filter: filter, bus: b}
+	var missed []model.LabEvent
+	if after > b.lastID {
+		// Курсор из прошлого запуска сервиса: история событий потеряна
+		sub.Truncated = true
+	} else if after > 0 && after < b.lastID {
+		ordered := b.ordered()
+		if len(ordered) > 0 && ordered[0].ID > after+1 {
+			sub.Truncated = true
+		}
+		for _, event := range ordered {
+			if event.ID > after && filter.match(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	sub.events = make(chan model.LabEvent, subscriberBuffer+len(missed))
+	for _, event := range missed {
+		sub.events <- event
+	}
+	sub.Events = sub.events
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// LastID возвращает ID последнего опубликованного события
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
+
+func (b *Bus) drop(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// История от старых событий к новым
+func (b *Bus) ordered() []model.LabEvent {
+	if len(b.history) < b.size {
+		return b.history
+	}
+	ordered := make([]model.LabEvent, 0, b.size)
+	ordered = append(ordered, b.history[b.next:]...)
+	return append(ordered, b.history[:b.next]...)
+}
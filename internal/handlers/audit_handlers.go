@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"lab/internal/middleware"
+	"lab/internal/model"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AuditHandler struct {
+	AuditService *service.AuditService
+	LabService   *service.LabService
+	Logger       *slog.Logger
+}
+
+// Конструктор для AuditHandler
+func NewAuditHandler(auditService *service.AuditService, labService *service.LabService, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{
+		AuditService: auditService,
+		LabService:   labService,
+		Logger:       logger,
+	}
+}
+
+// Обработчик для получения журнала команд лаборатории, только для владельца лаборатории и администратора
+func (h *AuditHandler) GetLabAuditHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.LabService.GetOwnLab(c.Request.Context(), uint(labID), middleware.UserID(c), middleware.IsAdmin(c)); err != nil {
+		h.Logger.ErrorContext(c, "Error getting lab info", "error", err)
+		switch {
+		case errors.Is(err, service.ErrLabForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Lab is available only to its owner"})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		}
+		return
+	}
+	filter.LabID = uint(labID)
+
+	h.respondAudits(c, filter)
+}
+
+// Обработчик для поиска по журналу команд всех лабораторий.
+// Фильтры: lab_id, user_id, from, to (RFC 3339), command (подстрока), limit, offset.
+func (h *AuditHandler) SearchAuditHandler(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if value := c.Query("lab_id"); value != "" {
+		labID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab_id"})
+			return
+		}
+		filter.LabID = uint(labID)
+	}
+
+	h.respondAudits(c, filter)
+}
+
+func (h *AuditHandler) respondAudits(c *gin.Context, filter model.ExecAuditFilter) {
+	audits, err := h.AuditService.FindExecs(c.Request.Context(), filter)
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to get exec audit", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get exec audit"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"audit": audits})
+}
+
+// Разбирает общие параметры фильтра журнала из query
+func parseAuditFilter(c *gin.Context) (model.ExecAuditFilter, error) {
+	filter := model.ExecAuditFilter{Command: c.Query("command")}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = uint(userID)
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New("invalid " + param.name + ": expected RFC 3339 time")
+			}
+			*param.target = parsed
+		}
+	}
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return filter, errors.New("invalid " + param.name)
+			}
+			*param.target = parsed
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"lab/internal/middleware"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type CheckHandler struct {
+	CheckService *service.CheckService
+	Logger       *slog.Logger
+}
+
+// Конструктор для CheckHandler
+func NewCheckHandler(checkService *service.CheckService, logger *slog.Logger) *CheckHandler {
+	return &CheckHandler{
+		CheckService: checkService,
+		Logger:       logger,
+	}
+}
+
+// Обработчик для запуска автоматической проверки лаборатории
+func (h *CheckHandler) CheckLabHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+
+	result, err := h.CheckService.RunChecks(c.Request.Context(), uint(labID), middleware.UserID(c))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to check lab", "error", err, "lab_id", labID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrNoChecks):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Task has no checks"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check lab"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// Обработчик для получения истории проверок лаборатории
+func (h *CheckHandler) GetCheckHistoryHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+
+	results, err := h.CheckService.GetCheckHistory(c.Request.Context(), uint(labID))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to get check history", "error", err, "lab_id", labID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get check history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
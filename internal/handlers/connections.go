@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"sync"
+	"time"
+)
+
+// Connections отслеживает долгоживущие соединения (WebSocket и SSE).
+// http.Server.Shutdown не закрывает перехваченные соединения и ждёт потоки SSE бесконечно,
+// поэтому при остановке сервиса их закрывает CloseAll.
+type Connections struct {
+	mu      sync.Mutex
+	conns   map[*websocket.Conn]struct{}
+	closing chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func NewConnections() *Connections {
+	return &Connections{
+		conns:   make(map[*websocket.Conn]struct{}),
+		closing: make(chan struct{}),
+	}
+}
+
+// Track регистрирует WebSocket-соединение. Возвращает false, если сервис уже останавливается.
+// Функцию untrack нужно вызвать, когда обработчик соединения завершился.
+func (c *Connections) Track(conn *websocket.Conn) (untrack func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, false
+	}
+	c.conns[conn] = struct{}{}
+	c.wg.Add(1)
+	return func() {
+		c.mu.Lock()
+		delete(c.conns, conn)
+		c.mu.Unlock()
+		c.wg.Done()
+	}, true
+}
+
+// Closing закрывается в начале остановки сервиса; по нему завершаются потоки SSE
+func (c *Connections) Closing() <-chan struct{} {
+	return c.closing
+}
+
+// CloseAll отправляет клиентам WebSocket кадр закрытия «going away», закрывает соединения
+// и ждёт завершения их обработчиков, пока не истечёт ctx
+func (c *Connections) CloseAll(ctx context.Context) {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.closing)
+	}
+	for conn := range c.conns {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+		conn.Close()
+	}
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"lab/internal/events"
+	"lab/internal/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Интервал пустых сообщений, чтобы прокси не закрывали неактивное соединение
+const eventHeartbeat = 25 * time.Second
+
+type EventHandler struct {
+	Bus         *events.Bus
+	Connections *Connections
+	Upgrader    websocket.Upgrader
+	Logger      *slog.Logger
+}
+
+// Конструктор для EventHandler
+func NewEventHandler(bus *events.Bus, connections *Connections, allowedOrigins []string, logger *slog.Logger) *EventHandler {
+	return &EventHandler{
+		Bus:         bus,
+		Connections: connections,
+		Upgrader:    newUpgrader(allowedOrigins),
+		Logger:      logger,
+	}
+}
+
+// Обработчик потока событий лабораторий: WebSocket при запросе upgrade, иначе Server-Sent Events.
+// Фильтры: lab_id, owner_id. Курсор — параметр cursor или заголовок Last-Event-ID.
+// Нужна авторизация: обычный пользователь получает только события своих лабораторий,
+// поток без фильтра по владельцу доступен только администратору.
+func (h *EventHandler) EventsHandler(c *gin.Context) {
+	var filter events.Filter
+	for _, param := range []struct {
+		name   string
+		target *uint
+	}{{"lab_id", &filter.LabID}, {"owner_id", &filter.OwnerID}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param.name})
+				return
+			}
+			*param.target = uint(parsed)
+		}
+	}
+	if !middleware.IsAdmin(c) {
+		filter.OwnerID = middleware.UserID(c)
+	}
+
+	cursorValue := c.Query("cursor")
+	if cursorValue == "" {
+		cursorValue = c.GetHeader("Last-Event-ID")
+	}
+	var cursor uint64
+	if cursorValue != "" {
+		parsed, err := strconv.ParseUint(cursorValue, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		cursor = parsed
+	}
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c, filter, cursor)
+		return
+	}
+	h.serveSSE(c, filter, cursor)
+}
+
+func (h *EventHandler) serveSSE(c *gin.Context, filter events.Filter, cursor uint64) {
+	sub := h.Bus.Subscribe(filter, cursor)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if sub.Truncated {
+		fmt.Fprintf(c.Writer, "event: truncated\ndata: {\"last_id\":%d}\n\n", h.Bus.LastID())
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.Connections.Closing():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Подписчик отстал; клиент переподключится с Last-Event-ID
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.Logger.ErrorContext(c, "Failed to encode event", "error", err, "event_id", event.ID)
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (h *EventHandler) serveWebSocket(c *gin.Context, filter events.Filter, cursor uint64) {
+	conn, err := h.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to upgrade events connection", "error", err)
+		return
+	}
+	defer conn.Close()
+	untrack, ok := h.Connections.Track(conn)
+	if !ok {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+		return
+	}
+	defer untrack()
+
+	sub := h.Bus.Subscribe(filter, cursor)
+	defer sub.Close()
+
+	// Входящие сообщения не нужны, но их надо читать, чтобы заметить закрытие соединения
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if sub.Truncated {
+		if err := conn.WriteJSON(gin.H{"type": "truncated", "last_id": h.Bus.LastID()}); err != nil {
+			return
+		}
+	}
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber is too slow"), time.Now().Add(time.Second))
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
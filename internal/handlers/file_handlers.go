@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"io"
+	"lab/internal/middleware"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+type FileHandler struct {
+	FileService *service.FileService
+	Logger      *slog.Logger
+}
+
+// Конструктор для FileHandler
+func NewFileHandler(fileService *service.FileService, logger *slog.Logger) *FileHandler {
+	return &FileHandler{
+		FileService: fileService,
+		Logger:      logger,
+	}
+}
+
+// Обработчик для загрузки файла в контейнер лаборатории.
+// Тело с Content-Type application/x-tar распаковывается в каталог path, иначе записывается в файл path.
+func (h *FileHandler) UploadFileHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	filePath := c.Query("path")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+	mode, err := strconv.ParseInt(c.DefaultQuery("mode", "0644"), 8, 64)
+	if err != nil || mode < 0 || mode > 0o7777 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if c.ContentType() == "application/x-tar" {
+		err = h.FileService.UploadArchive(ctx, uint(labID), c.Query("service"), filePath, c.Request.Body)
+	} else {
+		err = h.FileService.UploadFile(ctx, uint(labID), c.Query("service"), filePath, c.Request.Body, mode)
+	}
+	if err != nil {
+		h.respondError(c, "Failed to upload file", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "File uploaded successfully", "path": path.Clean(filePath)})
+}
+
+// Обработчик для скачивания файла или каталога (tar или zip) из контейнера лаборатории
+func (h *FileHandler) DownloadFileHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	filePath := c.Query("path")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+	format := c.DefaultQuery("format", "tar")
+	if format != "tar" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be tar or zip"})
+		return
+	}
+
+	archive, err := h.FileService.OpenDownload(c.Request.Context(), uint(labID), c.Query("service"), filePath)
+	if err != nil {
+		h.respondError(c, "Failed to download file", err)
+		return
+	}
+	defer archive.Close()
+
+	name := path.Base(path.Clean(filePath))
+	switch archive.Root.Typeflag {
+	case tar.TypeReg:
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		c.Header("Content-Length", strconv.FormatInt(archive.Root.Size, 10))
+		c.Header("Content-Type", "application/octet-stream")
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(c.Writer, archive); err != nil {
+			h.Logger.ErrorContext(c, "Failed to stream file", "error", err, "lab_id", labID)
+		}
+	case tar.TypeDir:
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+		if format == "zip" {
+			c.Header("Content-Type", "application/zip")
+			c.Status(http.StatusOK)
+			err = archive.WriteZip(c.Writer, h.FileService.MaxDownloadBytes)
+		} else {
+			c.Header("Content-Type", "application/x-tar")
+			c.Status(http.StatusOK)
+			err = archive.WriteTar(c.Writer, h.FileService.MaxDownloadBytes)
+		}
+		// Заголовки уже отправлены, поэтому клиент увидит оборванный архив
+		if err != nil {
+			h.Logger.ErrorContext(c, "Failed to stream archive", "error", err, "lab_id", labID)
+		}
+	default:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Path is neither a file nor a directory"})
+	}
+}
+
+// Обработчик для листинга каталога контейнера
+func (h *FileHandler) ListDirHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+
+	listing, err := h.FileService.ListDir(c.Request.Context(), uint(labID), middleware.UserID(c), c.Query("service"), c.DefaultQuery("path", "/root"))
+	if err != nil {
+		h.respondError(c, "Failed to list directory", err)
+		return
+	}
+	c.JSON(http.StatusOK, listing)
+}
+
+// Обработчик для предпросмотра текстового файла
+func (h *FileHandler) PreviewFileHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	filePath := c.Query("path")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	file, err := h.FileService.ReadTextFile(c.Request.Context(), uint(labID), c.Query("service"), filePath)
+	if err != nil {
+		h.respondError(c, "Failed to read file", err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// Обработчик для сохранения небольшого текстового файла
+func (h *FileHandler) SaveFileHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	var request struct {
+		Path    string  `json:"path" binding:"required"`
+		Content *string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind file data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: expected {path: string, content: string}"})
+		return
+	}
+
+	if err := h.FileService.WriteTextFile(c.Request.Context(), uint(labID), c.Query("service"), request.Path, *request.Content); err != nil {
+		h.respondError(c, "Failed to save file", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "File saved successfully"})
+}
+
+// Переводит ошибку файлового сервиса в HTTP-ответ
+func (h *FileHandler) respondError(c *gin.Context, message string, err error) {
+	h.Logger.ErrorContext(c, message, "error", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+	case errors.Is(err, service.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+	case errors.Is(err, service.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	case errors.Is(err, service.ErrPathNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotDirectory), errors.Is(err, service.ErrNotTextFile):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"lab/internal/middleware"
+	"lab/internal/service"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type FlagHandler struct {
+	FlagService *service.FlagService
+	Logger      *slog.Logger
+}
+
+// Конструктор для FlagHandler
+func NewFlagHandler(flagService *service.FlagService, logger *slog.Logger) *FlagHandler {
+	return &FlagHandler{
+		FlagService: flagService,
+		Logger:      logger,
+	}
+}
+
+// Обработчик для сдачи флага из лаборатории
+func (h *FlagHandler) SubmitFlagHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	var request struct {
+		Flag string `json:"flag" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind flag submission", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: expected {flag: string}"})
+		return
+	}
+
+	result, err := h.FlagService.SubmitFlag(c.Request.Context(), uint(labID), middleware.UserID(c), request.Flag)
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to submit flag", "error", err, "lab_id", labID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrNoFlag):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Lab has no flag"})
+		case errors.Is(err, service.ErrTooManyAttempts):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, try again later"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit flag"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"correct": result.Correct})
+}
+
+// Обработчик для просмотра попыток сдачи флага, доступен администратору
+func (h *FlagHandler) GetSubmissionsHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+
+	submissions, err := h.FlagService.GetSubmissions(c.Request.Context(), uint(labID))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to get flag submissions", "error", err, "lab_id", labID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get flag submissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"submissions": submissions})
+}
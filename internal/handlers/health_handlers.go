@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"lab/internal/service"
+	"net/http"
+)
+
+type HealthHandler struct {
+	HealthService *service.HealthService
+}
+
+// Конструктор для HealthHandler
+func NewHealthHandler(healthService *service.HealthService) *HealthHandler {
+	return &HealthHandler{
+		HealthService: healthService,
+	}
+}
+
+// Liveness-проба: процесс жив и обрабатывает запросы, зависимости не проверяются
+func (h *HealthHandler) HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": service.HealthOK})
+}
+
+// Readiness-проба: 503, если не работает хотя бы один критичный компонент
+func (h *HealthHandler) ReadyzHandler(c *gin.Context) {
+	report := h.HealthService.Check(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"lab/internal/locks"
+	"lab/internal/middleware"
 	"lab/internal/model"
 	"lab/internal/service"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type LabHandler struct {
@@ -27,65 +31,60 @@ func NewLabHandler(labService *service.LabService, taskServiceURL string, logger
 	}
 }
 
-// Метод для проверки существования задания и получения Docker-образа
-func (h *LabHandler) checkTaskExists(taskID uint) (string, error) {
-	// Запрос к сервису заданий для получения Docker-образа для задания
-	url := fmt.Sprintf("%s/tasks/%d", h.TaskServiceURL, taskID)
-	resp, err := http.Get(url)
-	if err != nil {
-		h.Logger.Error("Error checking task existence", "error", err)
-		return "", fmt.Errorf("error checking task existence: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("task not found")
-	}
-
-	// Извлекаем путь к Docker-образу
-	var response struct {
-		Task struct {
-			VMImagePath string `json:"vm_image_path"`
-		} `json:"task"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		h.Logger.Error("Error unmarshaling task response", "error", err)
-		return "", fmt.Errorf("error unmarshaling task response: %w", err)
-	}
-
-	return response.Task.VMImagePath, nil
-}
-
 func (h *LabHandler) CreateLabHandler(c *gin.Context) {
 	if c.Request.ContentLength == 0 {
 		h.Logger.ErrorContext(c, "Empty request body")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Request body is empty"})
 		return
 	}
+	// Образ, порты, лимиты и тип терминала берутся из сервиса заданий.
+	// Переопределить их может только администратор.
 	var request struct {
-		TaskID      uint   `json:"task_id" binding:"required"`
-		VMImagePath string `json:"vm_image_path" binding:"required"`
+		TaskID   uint                  `json:"task_id" binding:"required"`
+		Title    string                `json:"title"`
+		Labels   map[string]string     `json:"labels"`
+		Override *model.TaskDefinition `json:"override"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
 		h.Logger.ErrorContext(c, "Failed to bind request data", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid JSON: expected {task_id: number, vm_image_path: string}",
+			"error": "Invalid JSON: expected {task_id: number}",
 		})
 		return
 	}
+	if request.Override != nil && !middleware.IsAdmin(c) {
+		h.Logger.WarnContext(c, "Non-admin tried to override task definition", "task_id", request.TaskID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admin can override task definition"})
+		return
+	}
 	h.Logger.InfoContext(c, "Creating lab",
 		"task_id", request.TaskID,
-		"vm_image_path", request.VMImagePath,
+		"override", request.Override != nil,
 	)
 	ctx := c.Request.Context()
-	containerID, accessURL, labID, err := h.LabService.CreateLab(ctx, request.TaskID, request.VMImagePath)
+	containerID, accessURL, labID, err := h.LabService.CreateLab(ctx, request.TaskID, middleware.UserID(c), request.Title, request.Labels, request.Override)
 	if err != nil {
 		h.Logger.ErrorContext(c, "Failed to create lab", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to start lab container",
-			"details": err.Error(), // Можно убрать в production
-		})
+		switch {
+		case errors.Is(err, service.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrInvalidTask):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid task definition", "details": err.Error()})
+		case errors.Is(err, service.ErrInvalidLabels):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid labels", "details": err.Error()})
+		case errors.Is(err, service.ErrQuotaExceeded):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Lab quota exceeded"})
+		case errors.Is(err, service.ErrOwnerRequired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to start lab container",
+				"details": err.Error(), // Можно убрать в production
+			})
+		}
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -94,24 +93,113 @@ func (h *LabHandler) CreateLabHandler(c *gin.Context) {
 		"lab_id":       labID,
 	})
 }
+
+// Обработчик частичного обновления лаборатории. Меняются только title, labels, notes и ttl_minutes.
+// Заголовок If-Match с ETag из GET /labs/:id обязателен и защищает от одновременных правок: при устаревшей
+// версии — 409, без заголовка — 428. If-Match: * обновляет лабораторию без проверки версии.
 func (h *LabHandler) UpdateLabHandler(c *gin.Context) {
-	var lab model.Lab
-	if err := c.ShouldBindJSON(&lab); err != nil {
-		h.Logger.ErrorContext(c, "Failed to bind lab data", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Lab Data"})
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	version, err := parseIfMatch(c.GetHeader("If-Match"))
+	if errors.Is(err, errIfMatchRequired) {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx := c.Request.Context()
-	err := h.LabService.UpdateLab(ctx, &lab)
+	var request struct {
+		Title      *string            `json:"title"`
+		Labels     *map[string]string `json:"labels"`
+		Notes      *string            `json:"notes"`
+		TTLMinutes *int               `json:"ttl_minutes"`
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind lab update", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON: only title, labels, notes and ttl_minutes can be updated",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	lab, err := h.LabService.PatchLab(c.Request.Context(), uint(labID), version, model.LabPatch{
+		Title:      request.Title,
+		Labels:     request.Labels,
+		Notes:      request.Notes,
+		TTLMinutes: request.TTLMinutes,
+	})
 	if err != nil {
 		h.Logger.ErrorContext(c, "Failed to update lab", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lab"})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrVersionConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab was modified by another request", "details": err.Error()})
+		case errors.Is(err, service.ErrLabTrashed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab is in trash"})
+		case errors.Is(err, locks.ErrInProgress):
+			respondOperationInProgress(c, err)
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		case errors.Is(err, service.ErrInvalidPatch), errors.Is(err, service.ErrInvalidLabels):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid lab update", "details": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lab"})
+		}
 		return
 	}
 
-	h.Logger.InfoContext(c, "Lab updated", "lab", lab)
-	c.JSON(http.StatusOK, gin.H{"message": "Lab updated successfully"})
+	h.Logger.InfoContext(c, "Lab updated", "lab_id", lab.ID, "version", lab.Version)
+	c.Header("ETag", labETag(lab))
+	c.JSON(http.StatusOK, gin.H{"lab": lab})
+}
+
+// Ответ на операцию над лабораторией, которую не дождалась другая операция.
+// В ответе — ID и тип выполняющейся операции, чтобы клиент мог сообщить, чем занята лаборатория.
+func respondOperationInProgress(c *gin.Context, err error) {
+	response := gin.H{"error": "Operation in progress"}
+	var inProgress *locks.InProgressError
+	if errors.As(err, &inProgress) {
+		response["operation_id"] = inProgress.Current.ID
+		response["operation"] = inProgress.Current
+	}
+	c.JSON(http.StatusConflict, response)
+}
+
+// ETag лаборатории — её версия
+func labETag(lab *model.Lab) string {
+	return strconv.Quote(strconv.FormatUint(uint64(lab.Version), 10))
+}
+
+var errIfMatchRequired = errors.New("If-Match is required: pass the ETag from GET /labs/:id, or * to skip the version check")
+
+// Разбирает If-Match. Без заголовка возвращает errIfMatchRequired, "*" не проверяет версию
+func parseIfMatch(header string) (uint, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, errIfMatchRequired
+	}
+	if header == "*" {
+		return 0, nil
+	}
+	tag, err := strconv.Unquote(strings.TrimPrefix(header, "W/"))
+	if err != nil {
+		return 0, errors.New("invalid If-Match: expected a single ETag from GET /labs/:id")
+	}
+	version, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || version == 0 {
+		return 0, errors.New("invalid If-Match: unknown ETag " + header)
+	}
+	return uint(version), nil
 }
 
 // Обработчик для удаления лаборатории
@@ -128,12 +216,144 @@ func (h *LabHandler) DeleteLabHandler(c *gin.Context) {
 	err = h.LabService.DeleteLab(ctx, labID)
 	if err != nil {
 		h.Logger.ErrorContext(c, "Failed to delete lab", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lab"})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrLabTrashed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab is already in trash"})
+		case errors.Is(err, locks.ErrInProgress):
+			respondOperationInProgress(c, err)
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lab"})
+		}
+		return
+	}
+
+	h.Logger.InfoContext(c, "Lab moved to trash", "lab_id", labID)
+	c.JSON(http.StatusOK, gin.H{"message": "Laboratory moved to trash"})
+}
+
+// Обработчик восстановления лаборатории из корзины
+func (h *LabHandler) RestoreLabHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
 		return
 	}
 
-	h.Logger.InfoContext(c, "Lab deleted", "lab_id", labID)
-	c.JSON(http.StatusOK, gin.H{"message": "Laboratory deleted successfully"})
+	lab, err := h.LabService.RestoreLab(c.Request.Context(), uint(labID))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to restore lab", "error", err)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrLabNotTrashed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab is not in trash"})
+		case errors.Is(err, service.ErrRetentionExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "Lab retention period has passed"})
+		case errors.Is(err, locks.ErrInProgress):
+			respondOperationInProgress(c, err)
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore lab"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, lab)
+}
+
+// Обработчик списка лабораторий с постраничной выдачей.
+// Фильтры: task_id, owner_id, status, created_from, created_to (RFC 3339), label (key=value или key, можно несколько).
+// Сортировка: sort=created_at|updated_at, order=asc|desc. Страница: limit и cursor из next_cursor.
+// Обычный пользователь видит только свои лаборатории.
+func (h *LabHandler) ListLabsHandler(c *gin.Context) {
+	filter, err := parseLabFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if userID := middleware.UserID(c); userID != 0 && !middleware.IsAdmin(c) {
+		filter.OwnerID = userID
+	}
+
+	page, err := h.LabService.ListLabs(c.Request.Context(), filter, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidLabels) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.Logger.ErrorContext(c, "Failed to list labs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list labs"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// Разбирает фильтр и сортировку списка лабораторий из query
+func parseLabFilter(c *gin.Context) (model.LabFilter, error) {
+	filter := model.LabFilter{Status: c.Query("status"), SortBy: c.Query("sort")}
+	for _, param := range []struct {
+		name   string
+		target *uint
+	}{{"task_id", &filter.TaskID}, {"owner_id", &filter.OwnerID}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return filter, errors.New("invalid " + param.name)
+			}
+			*param.target = uint(parsed)
+		}
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"created_from", &filter.CreatedFrom}, {"created_to", &filter.CreatedTo}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New("invalid " + param.name + ": expected RFC 3339 time")
+			}
+			*param.target = parsed
+		}
+	}
+	for _, label := range c.QueryArray("label") {
+		key, value, hasValue := strings.Cut(label, "=")
+		if key == "" {
+			return filter, errors.New("invalid label: expected key=value or key")
+		}
+		if !hasValue {
+			filter.LabelKeys = append(filter.LabelKeys, key)
+			continue
+		}
+		if filter.Labels == nil {
+			filter.Labels = map[string]string{}
+		}
+		filter.Labels[key] = value
+	}
+	switch filter.SortBy {
+	case "", model.LabSortCreatedAt, model.LabSortUpdatedAt:
+	default:
+		return filter, errors.New("invalid sort: expected created_at or updated_at")
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errors.New("invalid order: expected asc or desc")
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
 }
 
 // Обработчик для получения лаборатории по ID
@@ -155,6 +375,7 @@ func (h *LabHandler) GetLabHandler(c *gin.Context) {
 	}
 
 	h.Logger.InfoContext(c, "Lab found", "lab", lab)
+	c.Header("ETag", labETag(lab))
 	c.JSON(http.StatusOK, gin.H{"lab": lab})
 }
 
@@ -168,24 +389,37 @@ func (h *LabHandler) StartLabHandler(c *gin.Context) {
 		return
 	}
 
-	// Получаем Docker-образ через API
-	vmImagePath, err := h.checkTaskExists(uint(labID))
-	if err != nil {
-		h.Logger.ErrorContext(c, "Failed to get task Docker image", "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		return
-	}
-
 	ctx := c.Request.Context()
-	containerID, err := h.LabService.StartLab(ctx, &model.Lab{ID: uint(labID)}, vmImagePath)
+	result, err := h.LabService.StartLab(ctx, uint(labID))
 	if err != nil {
 		h.Logger.ErrorContext(c, "Failed to start container", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start container"})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrLabExpired):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab has expired"})
+		case errors.Is(err, service.ErrLabTrashed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab is in trash"})
+		case errors.Is(err, locks.ErrInProgress):
+			respondOperationInProgress(c, err)
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start container"})
+		}
 		return
 	}
 
-	h.Logger.InfoContext(c, "Lab started successfully", "container_id", containerID)
-	c.JSON(http.StatusOK, gin.H{"message": "Laboratory started successfully", "container_id": containerID})
+	h.Logger.InfoContext(c, "Lab started successfully", "container_id", result.ContainerID, "mode", result.Mode)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Laboratory started successfully",
+		"container_id": result.ContainerID,
+		"access_url":   result.AccessURL,
+		"mode":         result.Mode,
+		"image":        result.Image,
+	})
 }
 
 // Обработчик для остановки лаборатории
@@ -202,7 +436,18 @@ func (h *LabHandler) StopLabHandler(c *gin.Context) {
 	err = h.LabService.StopLab(ctx, labID)
 	if err != nil {
 		h.Logger.ErrorContext(c, "Error stopping lab", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not stop lab"})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		case errors.Is(err, service.ErrLabTrashed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab is in trash"})
+		case errors.Is(err, locks.ErrInProgress):
+			respondOperationInProgress(c, err)
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not stop lab"})
+		}
 		return
 	}
 
@@ -212,6 +457,7 @@ func (h *LabHandler) StopLabHandler(c *gin.Context) {
 func (h *LabHandler) ExecuteCommandHandler(c *gin.Context) {
 	var request struct {
 		ContainerID string `json:"container_id"`
+		Service     string `json:"service"` // Сервис лаборатории из нескольких контейнеров
 		Command     string `json:"command"`
 	}
 
@@ -221,14 +467,40 @@ func (h *LabHandler) ExecuteCommandHandler(c *gin.Context) {
 		return
 	}
 
-	if request.ContainerID == "" || request.Command == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "container_id and command are required"})
+	if request.Command == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "command is required"})
+		return
+	}
+
+	// Команда выполняется в контейнере лаборатории из пути, при необходимости в указанном сервисе.
+	// container_id, если передан, должен совпадать с этим контейнером.
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	lab, err := h.LabService.GetLab(c.Request.Context(), uint(labID))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Error getting lab info", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		return
+	}
+	containerID, err := h.LabService.ResolveContainer(lab, request.Service)
+	if err != nil {
+		h.Logger.ErrorContext(c, "Unknown lab service", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if request.ContainerID != "" && request.ContainerID != containerID {
+		h.Logger.WarnContext(c, "Container does not belong to the lab", "lab_id", labID, "container_id", request.ContainerID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Container does not belong to the lab"})
 		return
 	}
 
 	commandArgs := strings.Fields(request.Command)
 
-	output, _ := h.LabService.ExecuteCommand(c.Request.Context(), request.ContainerID, commandArgs)
+	output, _ := h.LabService.ExecuteCommand(c.Request.Context(), uint(labID), middleware.UserID(c), containerID, commandArgs)
 
 	c.JSON(http.StatusOK, gin.H{"output": strings.TrimSpace(output)})
 }
@@ -254,25 +526,37 @@ func (h *LabHandler) CommitLabHandler(c *gin.Context) {
 		return
 	}
 
-	imageName, err := h.LabService.CommitLab(ctx, lab.ContainerName)
+	imageName, images, err := h.LabService.SnapshotLab(ctx, lab)
 	if err != nil {
 		h.Logger.ErrorContext(c, "Error committing container",
 			"error", err,
 			"container", lab.ContainerName)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not commit container"})
+		switch {
+		case errors.Is(err, service.ErrLabTrashed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Lab is in trash"})
+		case errors.Is(err, locks.ErrInProgress):
+			respondOperationInProgress(c, err)
+		case errors.Is(err, service.ErrShuttingDown):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service is shutting down"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not commit container"})
+		}
 		return
 	}
-	lab.CommitImage = imageName
 
 	h.Logger.InfoContext(c, "Container committed successfully",
 		"lab_id", labID,
 		"container", lab.ContainerName,
 		"image_name", imageName)
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":    "Container committed successfully",
 		"image_name": imageName,
-	})
+	}
+	if images != nil {
+		response["images"] = images
+	}
+	c.JSON(http.StatusOK, response)
 }
 func (h *LabHandler) DeleteCommitLabHandler(c *gin.Context) {
 	labIDParam := c.Param("id")
@@ -289,7 +573,11 @@ func (h *LabHandler) DeleteCommitLabHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not get lab info"})
 		return
 	}
-	err = h.LabService.DeleteContainerCommits(ctx, lab.ContainerName)
+	if len(lab.Containers) > 0 {
+		err = h.LabService.DeleteLabGroupCommits(ctx, lab)
+	} else {
+		err = h.LabService.DeleteContainerCommits(ctx, lab.ContainerName)
+	}
 	if err != nil {
 		h.Logger.ErrorContext(c, "Error deleting container", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete container"})
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"lab/internal/model"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type TemplateHandler struct {
+	TemplateService *service.TemplateService
+	Logger          *slog.Logger
+}
+
+// Конструктор для TemplateHandler
+func NewTemplateHandler(templateService *service.TemplateService, logger *slog.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		TemplateService: templateService,
+		Logger:          logger,
+	}
+}
+
+// Обработчик для создания шаблона лаборатории
+func (h *TemplateHandler) CreateTemplateHandler(c *gin.Context) {
+	var template model.LabTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind template data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template data"})
+		return
+	}
+	template.ID = 0
+
+	if err := h.TemplateService.CreateTemplate(c.Request.Context(), &template); err != nil {
+		h.respondError(c, "Failed to create template", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"template": template})
+}
+
+// Обработчик для обновления шаблона лаборатории
+func (h *TemplateHandler) UpdateTemplateHandler(c *gin.Context) {
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse template id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return
+	}
+	var template model.LabTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind template data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template data"})
+		return
+	}
+	template.ID = uint(templateID)
+
+	if err := h.TemplateService.UpdateTemplate(c.Request.Context(), &template); err != nil {
+		h.respondError(c, "Failed to update template", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+// Обработчик для удаления шаблона лаборатории
+func (h *TemplateHandler) DeleteTemplateHandler(c *gin.Context) {
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse template id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return
+	}
+
+	if err := h.TemplateService.DeleteTemplate(c.Request.Context(), uint(templateID)); err != nil {
+		h.respondError(c, "Failed to delete template", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// Обработчик для получения шаблона по ID
+func (h *TemplateHandler) GetTemplateHandler(c *gin.Context) {
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse template id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template id"})
+		return
+	}
+
+	template, err := h.TemplateService.GetTemplate(c.Request.Context(), uint(templateID))
+	if err != nil {
+		h.respondError(c, "Failed to get template", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+// Обработчик для получения всех шаблонов
+func (h *TemplateHandler) GetAllTemplatesHandler(c *gin.Context) {
+	templates, err := h.TemplateService.GetAllTemplates(c.Request.Context())
+	if err != nil {
+		h.respondError(c, "Failed to get templates", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// Переводит ошибку сервиса шаблонов в HTTP-ответ
+func (h *TemplateHandler) respondError(c *gin.Context, message string, err error) {
+	h.Logger.ErrorContext(c, message, "error", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+	case errors.Is(err, service.ErrInvalidTemplate):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
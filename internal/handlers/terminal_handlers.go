@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+	"lab/internal/middleware"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Создаёт upgrader для websocket терминала и событий. Фронтенд может быть на другом origin,
+// поэтому кроме своего хоста разрешены origin из allowedOrigins. Запросы без Origin приходят
+// не из браузера, и чужая страница не может выполнить их от имени пользователя.
+func newUpgrader(allowedOrigins []string) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" || slices.Contains(allowedOrigins, origin) {
+				return true
+			}
+			parsed, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(parsed.Host, r.Host)
+		},
+	}
+}
+
+// terminalMessage — управляющее сообщение клиента в текстовом кадре websocket
+type terminalMessage struct {
+	Type string `json:"type"` // input или resize
+	Data string `json:"data"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+type TerminalHandler struct {
+	TerminalService *service.TerminalService
+	Connections     *Connections
+	Upgrader        websocket.Upgrader
+	Logger          *slog.Logger
+}
+
+// Конструктор для TerminalHandler
+func NewTerminalHandler(terminalService *service.TerminalService, connections *Connections, allowedOrigins []string, logger *slog.Logger) *TerminalHandler {
+	return &TerminalHandler{
+		TerminalService: terminalService,
+		Connections:     connections,
+		Upgrader:        newUpgrader(allowedOrigins),
+		Logger:          logger,
+	}
+}
+
+// Обработчик терминала лаборатории через websocket, только для владельца лаборатории и администратора.
+// Бинарные кадры клиента — ввод, текстовые — JSON {type: input|resize}. Вывод отправляется бинарными кадрами.
+func (h *TerminalHandler) TerminalHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	cols, _ := strconv.Atoi(c.Query("cols"))
+	rows, _ := strconv.Atoi(c.Query("rows"))
+
+	session, err := h.TerminalService.OpenSession(c.Request.Context(), uint(labID), middleware.UserID(c), middleware.IsAdmin(c), c.Query("service"), cols, rows)
+	if err != nil {
+		h.respondError(c, "Failed to open terminal", err)
+		return
+	}
+	defer session.Close()
+
+	conn, err := h.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to upgrade terminal connection", "error", err, "lab_id", labID)
+		return
+	}
+	defer conn.Close()
+	untrack, ok := h.Connections.Track(conn)
+	if !ok {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+		return
+	}
+	defer untrack()
+
+	// Вывод терминала -> клиент; при завершении shell закрываем соединение
+	go func() {
+		buf := make([]byte, 8192)
+		for {
+			n, err := session.Read(buf)
+			if n > 0 {
+				if writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); writeErr != nil {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "terminal closed"), time.Now().Add(time.Second))
+		conn.Close()
+	}()
+
+	// Клиент -> ввод терминала
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if messageType == websocket.BinaryMessage {
+			if _, err := session.Write(data); err != nil {
+				break
+			}
+			continue
+		}
+		var message terminalMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			h.Logger.WarnContext(c, "Invalid terminal message", "error", err, "lab_id", labID)
+			continue
+		}
+		switch message.Type {
+		case "input":
+			if _, err := session.Write([]byte(message.Data)); err != nil {
+				return
+			}
+		case "resize":
+			if err := session.Resize(message.Cols, message.Rows); err != nil {
+				h.Logger.WarnContext(c, "Failed to resize terminal", "error", err, "lab_id", labID)
+			}
+		}
+	}
+	h.Logger.InfoContext(c, "Terminal session closed", "lab_id", labID)
+}
+
+// Обработчик для получения списка записей терминальных сессий лаборатории
+func (h *TerminalHandler) GetRecordingsHandler(c *gin.Context) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+
+	recordings, err := h.TerminalService.GetRecordings(c.Request.Context(), uint(labID), middleware.UserID(c), middleware.IsAdmin(c))
+	if err != nil {
+		h.respondError(c, "Failed to get recordings", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recordings": recordings})
+}
+
+// Обработчик для скачивания записи в формате .cast
+func (h *TerminalHandler) DownloadRecordingHandler(c *gin.Context) {
+	h.serveRecording(c, "attachment")
+}
+
+// Обработчик для потокового воспроизведения записи в плеере asciinema
+func (h *TerminalHandler) StreamRecordingHandler(c *gin.Context) {
+	h.serveRecording(c, "inline")
+}
+
+// Отдаёт файл записи с поддержкой Range-запросов
+func (h *TerminalHandler) serveRecording(c *gin.Context, disposition string) {
+	labID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse lab id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab id"})
+		return
+	}
+	recordingID, err := strconv.Atoi(c.Param("rid"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse recording id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recording id"})
+		return
+	}
+
+	recording, file, err := h.TerminalService.OpenRecording(c.Request.Context(), uint(labID), uint(recordingID), middleware.UserID(c), middleware.IsAdmin(c))
+	if err != nil {
+		h.respondError(c, "Failed to open recording", err)
+		return
+	}
+	defer file.Close()
+
+	name := filepath.Base(recording.FilePath)
+	c.Header("Content-Type", "application/x-asciicast")
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, name))
+	http.ServeContent(c.Writer, c.Request, name, recording.StartedAt, file)
+}
+
+// Переводит ошибку терминального сервиса в HTTP-ответ
+func (h *TerminalHandler) respondError(c *gin.Context, message string, err error) {
+	h.Logger.ErrorContext(c, message, "error", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, service.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+	case errors.Is(err, service.ErrRecordingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording file not found"})
+	case errors.Is(err, service.ErrLabForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Lab is available only to its owner"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"lab/internal/model"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type WarmPoolHandler struct {
+	WarmPoolService *service.WarmPoolService
+	Logger          *slog.Logger
+}
+
+// Конструктор для WarmPoolHandler
+func NewWarmPoolHandler(warmPoolService *service.WarmPoolService, logger *slog.Logger) *WarmPoolHandler {
+	return &WarmPoolHandler{
+		WarmPoolService: warmPoolService,
+		Logger:          logger,
+	}
+}
+
+// Обработчик для создания или замены настроек тёплого пула задания
+func (h *WarmPoolHandler) SavePoolHandler(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("task_id"))
+	if err != nil || taskID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+	var pool model.WarmPool
+	if err := c.ShouldBindJSON(&pool); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind warm pool data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warm pool data"})
+		return
+	}
+	pool.TaskID = uint(taskID)
+
+	saved, err := h.WarmPoolService.SavePool(c.Request.Context(), &pool)
+	if err != nil {
+		h.respondError(c, "Failed to save warm pool", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pool": saved})
+}
+
+// Обработчик для получения настроек и состояния пула задания
+func (h *WarmPoolHandler) GetPoolHandler(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("task_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	pool, err := h.WarmPoolService.GetPool(c.Request.Context(), uint(taskID))
+	if err != nil {
+		h.respondError(c, "Failed to get warm pool", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pool": pool})
+}
+
+// Обработчик для получения всех пулов
+func (h *WarmPoolHandler) GetAllPoolsHandler(c *gin.Context) {
+	pools, err := h.WarmPoolService.GetAllPools(c.Request.Context())
+	if err != nil {
+		h.respondError(c, "Failed to get warm pools", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pools": pools})
+}
+
+// Обработчик для удаления пула; готовые лаборатории пула удаляются в фоне
+func (h *WarmPoolHandler) DeletePoolHandler(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("task_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	if err := h.WarmPoolService.DeletePool(c.Request.Context(), uint(taskID)); err != nil {
+		h.respondError(c, "Failed to delete warm pool", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Warm pool deleted successfully"})
+}
+
+// Переводит ошибку сервиса пулов в HTTP-ответ
+func (h *WarmPoolHandler) respondError(c *gin.Context, message string, err error) {
+	h.Logger.ErrorContext(c, message, "error", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Warm pool not found"})
+	case errors.Is(err, service.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, service.ErrInvalidWarmPool), errors.Is(err, service.ErrNotPoolable), errors.Is(err, service.ErrInvalidTask):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"lab/internal/model"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type WebhookHandler struct {
+	WebhookService *service.WebhookService
+	Logger         *slog.Logger
+}
+
+// Конструктор для WebhookHandler
+func NewWebhookHandler(webhookService *service.WebhookService, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		WebhookService: webhookService,
+		Logger:         logger,
+	}
+}
+
+// Тело запроса создания и обновления подписки. Подписка включена, если active не передан.
+type webhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	EventTypes  []string `json:"event_types"`
+	Secret      string   `json:"secret"`
+	Active      *bool    `json:"active"`
+	Description string   `json:"description"`
+}
+
+func (r webhookRequest) subscription() *model.WebhookSubscription {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return &model.WebhookSubscription{
+		URL:         r.URL,
+		EventTypes:  r.EventTypes,
+		Secret:      r.Secret,
+		Active:      active,
+		Description: r.Description,
+	}
+}
+
+// Обработчик для создания подписки на вебхук
+func (h *WebhookHandler) CreateWebhookHandler(c *gin.Context) {
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind webhook data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	subscription := request.subscription()
+	if err := h.WebhookService.CreateSubscription(c.Request.Context(), subscription); err != nil {
+		h.respondError(c, "Failed to create webhook", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"webhook": subscription})
+}
+
+// Обработчик для обновления подписки на вебхук
+func (h *WebhookHandler) UpdateWebhookHandler(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse webhook id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.Logger.ErrorContext(c, "Failed to bind webhook data", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	subscription := request.subscription()
+	subscription.ID = uint(webhookID)
+	if err := h.WebhookService.UpdateSubscription(c.Request.Context(), subscription); err != nil {
+		h.respondError(c, "Failed to update webhook", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": subscription})
+}
+
+// Обработчик для удаления подписки на вебхук
+func (h *WebhookHandler) DeleteWebhookHandler(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse webhook id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	if err := h.WebhookService.DeleteSubscription(c.Request.Context(), uint(webhookID)); err != nil {
+		h.respondError(c, "Failed to delete webhook", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// Обработчик для получения подписки на вебхук
+func (h *WebhookHandler) GetWebhookHandler(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse webhook id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	subscription, err := h.WebhookService.GetSubscription(c.Request.Context(), uint(webhookID))
+	if err != nil {
+		h.respondError(c, "Failed to get webhook", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": subscription})
+}
+
+// Обработчик для получения всех подписок на вебхуки
+func (h *WebhookHandler) GetAllWebhooksHandler(c *gin.Context) {
+	subscriptions, err := h.WebhookService.GetAllSubscriptions(c.Request.Context())
+	if err != nil {
+		h.respondError(c, "Failed to get webhooks", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions})
+}
+
+// Обработчик для получения истории доставок подписки
+func (h *WebhookHandler) GetDeliveriesHandler(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse webhook id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	deliveries, err := h.WebhookService.GetDeliveries(c.Request.Context(), uint(webhookID))
+	if err != nil {
+		h.respondError(c, "Failed to get webhook deliveries", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// Обработчик для получения доставки со всеми попытками
+func (h *WebhookHandler) GetDeliveryHandler(c *gin.Context) {
+	deliveryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse delivery id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery id"})
+		return
+	}
+
+	delivery, err := h.WebhookService.GetDelivery(c.Request.Context(), uint(deliveryID))
+	if err != nil {
+		h.respondError(c, "Failed to get webhook delivery", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+// Обработчик для повторной отправки события доставки
+func (h *WebhookHandler) RedeliverHandler(c *gin.Context) {
+	deliveryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.Logger.ErrorContext(c, "Failed to parse delivery id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery id"})
+		return
+	}
+
+	delivery, err := h.WebhookService.Redeliver(c.Request.Context(), uint(deliveryID))
+	if err != nil {
+		h.respondError(c, "Failed to redeliver webhook", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+// Переводит ошибку сервиса вебхуков в HTTP-ответ
+func (h *WebhookHandler) respondError(c *gin.Context, message string, err error) {
+	h.Logger.ErrorContext(c, message, "error", err)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, service.ErrInvalidWebhook):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
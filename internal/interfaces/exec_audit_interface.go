@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type ExecAuditInterface interface {
+	CreateExecAudit(ctx context.Context, audit *model.ExecAudit) error
+	FindExecAudits(ctx context.Context, filter model.ExecAuditFilter) ([]*model.ExecAudit, error)
+}
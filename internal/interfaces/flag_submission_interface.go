@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type FlagSubmissionInterface interface {
+	CreateSubmission(ctx context.Context, submission *model.FlagSubmission) error
+	GetSubmissions(ctx context.Context, labID uint) ([]*model.FlagSubmission, error)
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+	"time"
+)
+
+type IdempotencyInterface interface {
+	// CreateRecord сохраняет запись, если ключа пользователя ещё нет; false — ключ уже занят
+	CreateRecord(ctx context.Context, record *model.IdempotencyRecord) (bool, error)
+	GetRecord(ctx context.Context, userID uint, key string) (*model.IdempotencyRecord, error)
+	UpdateRecord(ctx context.Context, record *model.IdempotencyRecord) error
+	DeleteRecord(ctx context.Context, id uint) error
+	DeleteExpiredRecords(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type LabCheckInterface interface {
+	CreateCheckResult(ctx context.Context, result *model.LabCheckResult) error
+	GetCheckResults(ctx context.Context, labID uint) ([]*model.LabCheckResult, error)
+}
@@ -3,12 +3,21 @@ package interfaces
 import (
 	"context"
 	"lab/internal/model"
+	"time"
 )
 
 type LabInterface interface {
 	CreateLab(ctx context.Context, lab *model.Lab) error
 	UpdateLab(ctx context.Context, lab *model.Lab) error
+	UpdateLabFields(ctx context.Context, lab *model.Lab, version uint, columns ...string) (bool, error)
 	DeleteLab(ctx context.Context, id int) error
 	GetLab(ctx context.Context, id int) (*model.Lab, error)
 	GetAllLabs(ctx context.Context) ([]*model.Lab, error)
+	ListLabs(ctx context.Context, filter model.LabFilter) ([]*model.Lab, error)
+	GetLabsByTask(ctx context.Context, taskID uint) ([]*model.Lab, error)
+	UpdateLabContainer(ctx context.Context, container *model.LabContainer) error
+	GetExpiredLabs(ctx context.Context, now time.Time) ([]*model.Lab, error)
+	GetTrashedLabs(ctx context.Context, before time.Time) ([]*model.Lab, error)
+	CountLabs(ctx context.Context) ([]model.LabCount, error)
+	CountOwnerLabs(ctx context.Context, ownerID uint) (int64, error)
 }
@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+	"time"
+)
+
+// LabLocker обеспечивает, что над одной лабораторией одновременно выполняется не больше одной операции,
+// а тёплый пул задания пополняет или сокращает не больше одной реплики
+type LabLocker interface {
+	// Lock захватывает блокировку лаборатории для операции. Если лаборатория занята, ждёт
+	// не дольше wait и возвращает ошибку, совпадающую с locks.ErrInProgress.
+	// unlock освобождает блокировку и вызывается ровно один раз.
+	Lock(ctx context.Context, labID uint, operation string, wait time.Duration) (op model.LabOperation, unlock func(), err error)
+	// LockPool захватывает блокировку тёплого пула задания без ожидания. acquired == false, если
+	// пул сейчас обрабатывает другой проход. unlock вызывается ровно один раз, если блокировка захвачена.
+	LockPool(ctx context.Context, taskID uint) (unlock func(), acquired bool, err error)
+}
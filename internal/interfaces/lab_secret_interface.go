@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type LabSecretInterface interface {
+	CreateSecrets(ctx context.Context, secrets []*model.LabSecret) error
+	GetSecrets(ctx context.Context, labID uint) ([]*model.LabSecret, error)
+	DeleteSecrets(ctx context.Context, labID uint) error
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type LabTemplateInterface interface {
+	CreateTemplate(ctx context.Context, template *model.LabTemplate) error
+	UpdateTemplate(ctx context.Context, template *model.LabTemplate) error
+	DeleteTemplate(ctx context.Context, id uint) error
+	GetTemplate(ctx context.Context, id uint) (*model.LabTemplate, error)
+	GetAllTemplates(ctx context.Context) ([]*model.LabTemplate, error)
+}
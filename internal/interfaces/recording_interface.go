@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type RecordingInterface interface {
+	CreateRecording(ctx context.Context, recording *model.TerminalRecording) error
+	UpdateRecording(ctx context.Context, recording *model.TerminalRecording) error
+	GetRecording(ctx context.Context, id uint) (*model.TerminalRecording, error)
+	GetRecordingsByLab(ctx context.Context, labID uint) ([]*model.TerminalRecording, error)
+}
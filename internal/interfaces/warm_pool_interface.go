@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type WarmPoolInterface interface {
+	SavePool(ctx context.Context, pool *model.WarmPool) error
+	GetPool(ctx context.Context, taskID uint) (*model.WarmPool, error)
+	GetAllPools(ctx context.Context) ([]*model.WarmPool, error)
+	DeletePool(ctx context.Context, taskID uint) error
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"lab/internal/model"
+)
+
+type WebhookInterface interface {
+	CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error
+	UpdateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id uint) error
+	GetSubscription(ctx context.Context, id uint) (*model.WebhookSubscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error)
+	GetActiveSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error)
+
+	CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, subscriptionID uint) ([]*model.WebhookDelivery, error)
+	GetPendingDeliveries(ctx context.Context) ([]*model.WebhookDelivery, error)
+	CreateAttempt(ctx context.Context, attempt *model.WebhookAttempt) error
+}
@@ -0,0 +1,72 @@
+package locks
+
+import (
+	"context"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"sync"
+	"time"
+)
+
+// Local — блокировки внутри процесса, достаточные, когда сервис запущен в одном экземпляре
+type Local struct {
+	mu    sync.Mutex
+	held  map[uint]*localLock
+	pools map[uint]bool // Задания, пулы которых сейчас обрабатываются
+}
+
+type localLock struct {
+	op       model.LabOperation
+	released chan struct{} // Закрывается при освобождении, будит ожидающих
+}
+
+func NewLocal() interfaces.LabLocker {
+	return &Local{held: map[uint]*localLock{}, pools: map[uint]bool{}}
+}
+
+func (l *Local) Lock(ctx context.Context, labID uint, operation string, wait time.Duration) (model.LabOperation, func(), error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		l.mu.Lock()
+		current, busy := l.held[labID]
+		if !busy {
+			lock := &localLock{op: newOperation(labID, operation), released: make(chan struct{})}
+			l.held[labID] = lock
+			l.mu.Unlock()
+			return lock.op, func() { l.release(labID, lock) }, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-current.released:
+		case <-timer.C:
+			return model.LabOperation{}, nil, &InProgressError{Current: current.op}
+		case <-ctx.Done():
+			return model.LabOperation{}, nil, ctx.Err()
+		}
+	}
+}
+
+func (l *Local) release(labID uint, lock *localLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[labID] == lock {
+		delete(l.held, labID)
+		close(lock.released)
+	}
+}
+
+func (l *Local) LockPool(ctx context.Context, taskID uint) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pools[taskID] {
+		return nil, false, nil
+	}
+	l.pools[taskID] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.pools, taskID)
+	}, true, nil
+}
@@ -0,0 +1,49 @@
+// Package locks содержит блокировки операций над лабораториями: внутри процесса
+// для одного узла и на advisory-блокировках Postgres для нескольких реплик.
+package locks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"lab/internal/model"
+	"os"
+	"time"
+)
+
+// Хранилища блокировок
+const (
+	BackendLocal    = "local"
+	BackendPostgres = "postgres"
+)
+
+var ErrInProgress = errors.New("another operation is in progress on the lab")
+
+// InProgressError сообщает, какая операция держит блокировку лаборатории
+type InProgressError struct {
+	Current model.LabOperation
+}
+
+func (e *InProgressError) Error() string {
+	return fmt.Sprintf("%s: lab %d is locked by %s operation %s since %s",
+		ErrInProgress, e.Current.LabID, e.Current.Operation, e.Current.ID, e.Current.StartedAt.Format(time.RFC3339))
+}
+
+func (e *InProgressError) Is(target error) bool {
+	return target == ErrInProgress
+}
+
+// Описание новой операции со случайным ID
+func newOperation(labID uint, operation string) model.LabOperation {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	node, _ := os.Hostname()
+	return model.LabOperation{
+		ID:        hex.EncodeToString(buf),
+		LabID:     labID,
+		Operation: operation,
+		Node:      node,
+		StartedAt: time.Now(),
+	}
+}
@@ -0,0 +1,136 @@
+package locks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"time"
+)
+
+// Первая половина ключа advisory-блокировки лаборатории, вторая — ID лаборатории.
+// Отличается от ключа блокировки миграций.
+const lockClass = 0x4c4f434b
+
+// Первая половина ключа advisory-блокировки тёплого пула, вторая — ID задания
+const poolLockClass = 0x504f4f4c
+
+// Как часто повторяется попытка захвата занятой блокировки
+const pollInterval = 100 * time.Millisecond
+
+// Postgres — блокировки на advisory-блокировках сессии, общие для всех реплик сервиса.
+// Блокировка держится на отдельном соединении до конца операции и снимается сама, если
+// реплика упала. Описание текущей операции хранится в lab_operations, чтобы другие реплики
+// могли сообщить, чем занята лаборатория.
+type Postgres struct {
+	DB     *sql.DB
+	Logger *slog.Logger
+}
+
+func NewPostgres(db *sql.DB, logger *slog.Logger) interfaces.LabLocker {
+	return &Postgres{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+func (p *Postgres) Lock(ctx context.Context, labID uint, operation string, wait time.Duration) (model.LabOperation, func(), error) {
+	conn, err := p.DB.Conn(ctx)
+	if err != nil {
+		return model.LabOperation{}, nil, fmt.Errorf("failed to get connection for lab lock: %w", err)
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", lockClass, int32(labID)).Scan(&acquired)
+		if err != nil {
+			conn.Close()
+			return model.LabOperation{}, nil, fmt.Errorf("failed to acquire lab lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			conn.Close()
+			return model.LabOperation{}, nil, &InProgressError{Current: p.current(ctx, labID)}
+		}
+		select {
+		case <-time.After(min(pollInterval, time.Until(deadline))):
+		case <-ctx.Done():
+			conn.Close()
+			return model.LabOperation{}, nil, ctx.Err()
+		}
+	}
+
+	op := newOperation(labID, operation)
+	// Запись предыдущего владельца могла остаться, если его реплика упала
+	_, err = conn.ExecContext(ctx, `INSERT INTO lab_operations (lab_id, operation_id, operation, node, started_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (lab_id) DO UPDATE SET operation_id = EXCLUDED.operation_id, operation = EXCLUDED.operation,
+			node = EXCLUDED.node, started_at = EXCLUDED.started_at`,
+		labID, op.ID, op.Operation, op.Node, op.StartedAt)
+	if err != nil {
+		p.Logger.WarnContext(ctx, "Failed to record lab operation", "error", err, "lab_id", labID, "operation_id", op.ID)
+	}
+	return op, func() { p.unlock(context.WithoutCancel(ctx), conn, op) }, nil
+}
+
+func (p *Postgres) unlock(ctx context.Context, conn *sql.Conn, op model.LabOperation) {
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "DELETE FROM lab_operations WHERE lab_id = $1 AND operation_id = $2", op.LabID, op.ID); err != nil {
+		p.Logger.WarnContext(ctx, "Failed to clear lab operation", "error", err, "lab_id", op.LabID, "operation_id", op.ID)
+	}
+	var released bool
+	err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1, $2)", lockClass, int32(op.LabID)).Scan(&released)
+	if err != nil || !released {
+		// Соединение с неснятой блокировкой нельзя возвращать в пул: следующий владелец унаследует её
+		p.Logger.ErrorContext(ctx, "Failed to release lab lock", "error", err, "lab_id", op.LabID, "operation_id", op.ID)
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+// LockPool держит блокировку пула на отдельном соединении, как и Lock, но не ждёт её и не записывает
+// операцию: пул, занятый другой репликой, обрабатывается при следующем проходе
+func (p *Postgres) LockPool(ctx context.Context, taskID uint) (func(), bool, error) {
+	conn, err := p.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for pool lock: %w", err)
+	}
+	var acquired bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", poolLockClass, int32(taskID)).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to acquire pool lock: %w", err)
+		}
+		return nil, false, nil
+	}
+	return func() { p.unlockPool(context.WithoutCancel(ctx), conn, taskID) }, true, nil
+}
+
+func (p *Postgres) unlockPool(ctx context.Context, conn *sql.Conn, taskID uint) {
+	defer conn.Close()
+	var released bool
+	err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1, $2)", poolLockClass, int32(taskID)).Scan(&released)
+	if err != nil || !released {
+		p.Logger.ErrorContext(ctx, "Failed to release pool lock", "error", err, "task_id", taskID)
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+// Описание операции, которая держит блокировку. Если записи нет (владелец только что
+// захватил блокировку или уже отпустил её), возвращается операция без ID.
+func (p *Postgres) current(ctx context.Context, labID uint) model.LabOperation {
+	op := model.LabOperation{LabID: labID}
+	err := p.DB.QueryRowContext(ctx,
+		"SELECT operation_id, operation, node, started_at FROM lab_operations WHERE lab_id = $1", labID).
+		Scan(&op.ID, &op.Operation, &op.Node, &op.StartedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		p.Logger.WarnContext(ctx, "Failed to read current lab operation", "error", err, "lab_id", labID)
+	}
+	return op
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const redacted = "[REDACTED]"
+
+// Redactor — обёртка над slog.Handler, которая вырезает зарегистрированные секретные значения
+// из сообщения и строковых атрибутов записи
+type Redactor struct {
+	handler slog.Handler
+	secrets *secretSet
+}
+
+type secretSet struct {
+	mu     sync.RWMutex
+	values map[string]struct{}
+}
+
+func NewRedactor(handler slog.Handler) *Redactor {
+	return &Redactor{
+		handler: handler,
+		secrets: &secretSet{values: make(map[string]struct{})},
+	}
+}
+
+// Add регистрирует значения, которые нельзя выводить в лог
+func (r *Redactor) Add(values ...string) {
+	r.secrets.mu.Lock()
+	defer r.secrets.mu.Unlock()
+	for _, value := range values {
+		if value != "" {
+			r.secrets.values[value] = struct{}{}
+		}
+	}
+}
+
+// Remove снимает регистрацию значений, например после удаления лаборатории
+func (r *Redactor) Remove(values ...string) {
+	r.secrets.mu.Lock()
+	defer r.secrets.mu.Unlock()
+	for _, value := range values {
+		delete(r.secrets.values, value)
+	}
+}
+
+// Redact заменяет зарегистрированные значения в строке, например в выводе команды перед сохранением
+func (r *Redactor) Redact(s string) string {
+	r.secrets.mu.RLock()
+	defer r.secrets.mu.RUnlock()
+	return r.redact(s)
+}
+
+func (r *Redactor) Enabled(ctx context.Context, level slog.Level) bool {
+	return r.handler.Enabled(ctx, level)
+}
+
+func (r *Redactor) Handle(ctx context.Context, record slog.Record) error {
+	r.secrets.mu.RLock()
+	defer r.secrets.mu.RUnlock()
+	if len(r.secrets.values) == 0 {
+		return r.handler.Handle(ctx, record)
+	}
+
+	clean := slog.NewRecord(record.Time, record.Level, r.redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		clean.AddAttrs(r.redactAttr(attr))
+		return true
+	})
+	return r.handler.Handle(ctx, clean)
+}
+
+func (r *Redactor) WithAttrs(attrs []slog.Attr) slog.Handler {
+	r.secrets.mu.RLock()
+	clean := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		clean[i] = r.redactAttr(attr)
+	}
+	r.secrets.mu.RUnlock()
+	return &Redactor{handler: r.handler.WithAttrs(clean), secrets: r.secrets}
+}
+
+func (r *Redactor) WithGroup(name string) slog.Handler {
+	return &Redactor{handler: r.handler.WithGroup(name), secrets: r.secrets}
+}
+
+// Вызывается под блокировкой чтения
+func (r *Redactor) redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, r.redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		clean := make([]any, len(group))
+		for i, inner := range group {
+			clean[i] = r.redactAttr(inner)
+		}
+		return slog.Group(attr.Key, clean...)
+	case slog.KindAny:
+		// Ошибки и структуры выводятся через fmt, поэтому проверяем их текстовое представление
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, r.redact(err.Error()))
+		}
+		return attr
+	default:
+		return attr
+	}
+}
+
+// Вызывается под блокировкой чтения
+func (r *Redactor) redact(s string) string {
+	for secret := range r.secrets.values {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
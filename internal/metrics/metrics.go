@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"lab/internal/model"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Результаты операций в метках outcome
+const (
+	OutcomeOK        = "ok"
+	OutcomeError     = "error"
+	OutcomeExitError = "exit_error" // Команда выполнилась, но завершилась с ненулевым кодом
+)
+
+var (
+	labOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lab_operations_total",
+		Help: "Lab lifecycle operations by operation and outcome.",
+	}, []string{"operation", "outcome"})
+	labOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lab_operation_duration_seconds",
+		Help:    "Duration of lab lifecycle operations.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"operation"})
+
+	runtimeCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lab_runtime_calls_total",
+		Help: "Container runtime CLI invocations by command and outcome.",
+	}, []string{"command", "outcome"})
+	runtimeCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lab_runtime_call_duration_seconds",
+		Help:    "Duration of container runtime CLI invocations.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"command"})
+
+	taskServiceRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lab_task_service_requests_total",
+		Help: "Requests to the task service by outcome.",
+	}, []string{"outcome"})
+	taskServiceDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "lab_task_service_request_duration_seconds",
+		Help:    "Duration of requests to the task service.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lab_http_requests_total",
+		Help: "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lab_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Handler отдаёт метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveOperation учитывает операцию над лабораторией, начатую в start
+func ObserveOperation(operation string, start time.Time, err error) {
+	labOperations.WithLabelValues(operation, outcome(err)).Inc()
+	labOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRuntime учитывает вызов CLI среды контейнеров, начатый в start
+func ObserveRuntime(command string, start time.Time, err error) {
+	runtimeCalls.WithLabelValues(command, outcome(err)).Inc()
+	runtimeCallDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+}
+
+// ObserveTaskService учитывает запрос к сервису заданий, начатый в start
+func ObserveTaskService(outcome string, start time.Time) {
+	taskServiceRequests.WithLabelValues(outcome).Inc()
+	taskServiceDuration.Observe(time.Since(start).Seconds())
+}
+
+// ObserveHTTP учитывает обработанный HTTP-запрос
+func ObserveHTTP(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func outcome(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.As(err, &exitErr):
+		return OutcomeExitError
+	default:
+		return OutcomeError
+	}
+}
+
+// RegisterPortUsage публикует занятые и все порты диапазона аллокатора
+func RegisterPortUsage(usage func() (used, total int)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "lab_port_allocator_used_ports",
+		Help: "Host ports currently allocated to lab terminals.",
+	}, func() float64 {
+		used, _ := usage()
+		return float64(used)
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "lab_port_allocator_total_ports",
+		Help: "Size of the host port range for lab terminals.",
+	}, func() float64 {
+		_, total := usage()
+		return float64(total)
+	})
+}
+
+// labCollector считает лаборатории по заданиям и состояниям на момент сбора метрик
+type labCollector struct {
+	count func(ctx context.Context) ([]model.LabCount, error)
+	desc  *prometheus.Desc
+}
+
+// RegisterLabCounts публикует число лабораторий по заданию и состоянию.
+// count вызывается при каждом сборе метрик.
+func RegisterLabCounts(count func(ctx context.Context) ([]model.LabCount, error)) {
+	prometheus.MustRegister(&labCollector{
+		count: count,
+		desc: prometheus.NewDesc("lab_labs", "Labs by task and status.",
+			[]string{"task_id", "status"}, nil),
+	})
+}
+
+func (c *labCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *labCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	counts, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, count := range counts {
+		status := count.Status
+		if status == "" {
+			status = "unknown"
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count.Count),
+			strconv.FormatUint(uint64(count.TaskID), 10), status)
+	}
+}
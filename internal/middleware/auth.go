@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contextUserID = "auth_user_id"
+	contextRole   = "auth_role"
+
+	RoleAdmin = "admin"
+)
+
+// Claims — поля JWT, которые выдаёт сервис пользователей
+type Claims struct {
+	UserID json.Number `json:"user_id"`
+	Role   string      `json:"role"`
+	Exp    int64       `json:"exp"`
+}
+
+// Auth разбирает Bearer-токен, если он передан, и кладёт пользователя в контекст запроса.
+// Запросы без токена пропускаются как анонимные, запросы с невалидным токеном отклоняются.
+func Auth(secret string, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+			return
+		}
+
+		claims, err := parseToken(token, secret)
+		if err != nil {
+			logger.WarnContext(c, "Invalid auth token", "error", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		userID, err := strconv.ParseUint(claims.UserID.String(), 10, 64)
+		if err != nil {
+			logger.WarnContext(c, "Invalid user id in token", "error", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		c.Set(contextUserID, uint(userID))
+		c.Set(contextRole, claims.Role)
+		c.Next()
+	}
+}
+
+// RequireAuth отклоняет анонимные запросы
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if UserID(c) == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin пропускает только запросы от администратора
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin role required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserID возвращает ID пользователя из токена или 0 для анонимного запроса
+func UserID(c *gin.Context) uint {
+	return c.GetUint(contextUserID)
+}
+
+// IsAdmin сообщает, выполнен ли запрос администратором
+func IsAdmin(c *gin.Context) bool {
+	return c.GetString(contextRole) == RoleAdmin
+}
+
+// Проверяет подпись HS256 и срок действия токена
+func parseToken(token, secret string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("unmarshal header: %w", err)
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("signature mismatch")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("unmarshal claims: %w", err)
+	}
+	if claims.Exp != 0 && time.Now().Unix() > claims.Exp {
+		return nil, errors.New("token expired")
+	}
+	return &claims, nil
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// Idempotency обрабатывает заголовок Idempotency-Key: первый запрос с ключом выполняется,
+// а его ответ сохраняется; повтор с тем же ключом и телом получает сохранённый ответ,
+// с другим телом — 422. Ключи разделены по пользователям. Ответы, после которых запрос
+// имеет смысл повторить (см. retryable), не сохраняются. Запросы без заголовка проходят как обычно,
+// как и анонимные: у них общий UserID 0, и сохранённый ответ достался бы другому анонимному клиенту.
+func Idempotency(idempotency *service.IdempotencyService, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || UserID(c) == 0 {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := idempotency.Begin(c.Request.Context(), UserID(c), key, c.Request.Method, c.Request.URL.Path, body)
+		switch {
+		case errors.Is(err, service.ErrInvalidIdempotencyKey):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid Idempotency-Key header"})
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			return
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Request with this Idempotency-Key is in progress"})
+			return
+		case err != nil:
+			logger.ErrorContext(c, "Failed to register idempotency key", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+		if replay {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.ResponseCode, record.ResponseType, record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Клиент мог отключиться, не дождавшись ответа: именно ему ответ и понадобится при повторе
+		ctx := context.WithoutCancel(c.Request.Context())
+		if status := writer.Status(); retryable(status) {
+			err = idempotency.Abandon(ctx, record)
+		} else {
+			err = idempotency.Complete(ctx, record, status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to save idempotent response", "error", err, "record_id", record.ID)
+		}
+	}
+}
+
+// Ответ, с которым операция не выполнена и может выполниться при повторе: ошибка сервера,
+// занятая другой операцией лаборатория (409) или превышение лимита запросов (429).
+// Сохранённый ответ воспроизводился бы вместо выполнения до конца срока хранения ключа.
+func retryable(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusConflict || status == http.StatusTooManyRequests
+}
+
+// Копирует тело ответа для сохранения, передавая его клиенту как обычно
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
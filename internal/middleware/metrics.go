@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"lab/internal/metrics"
+	"time"
+)
+
+// Metrics учитывает запросы по шаблону маршрута gin, а не по фактическому пути,
+// чтобы ID лабораторий не раздували число временных рядов
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTP(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
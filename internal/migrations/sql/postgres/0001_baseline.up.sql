@@ -0,0 +1,303 @@
+-- Схема, которую раньше создавал AutoMigrate. Базы, созданные до появления миграций,
+-- принимаются под управление: существующие таблицы не пересоздаются, а недостающие колонки
+-- добавляются через ADD COLUMN IF NOT EXISTS. Например, исходный AutoMigrate(&model.Lab{})
+-- создавал в labs только id, title, task_id, время, container_id, container_name, access_url и commit_image.
+
+CREATE TABLE IF NOT EXISTS labs (
+    id              bigserial PRIMARY KEY,
+    title           text,
+    task_id         bigint,
+    owner_id        bigint,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    container_id    text,
+    container_name  text,
+    access_url      text,
+    host_port       bigint,
+    commit_image    text,
+    image           text,
+    terminal_type   text,
+    ports           text,
+    cpu_limit       text,
+    memory_limit    text,
+    pids_limit      bigint,
+    env             text,
+    flag            text,
+    record_terminal boolean,
+    status          text,
+    expires_at      timestamptz,
+    template_id     bigint,
+    network_name    text
+);
+ALTER TABLE labs
+    ADD COLUMN IF NOT EXISTS title           text,
+    ADD COLUMN IF NOT EXISTS task_id         bigint,
+    ADD COLUMN IF NOT EXISTS owner_id        bigint,
+    ADD COLUMN IF NOT EXISTS created_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS container_id    text,
+    ADD COLUMN IF NOT EXISTS container_name  text,
+    ADD COLUMN IF NOT EXISTS access_url      text,
+    ADD COLUMN IF NOT EXISTS host_port       bigint,
+    ADD COLUMN IF NOT EXISTS commit_image    text,
+    ADD COLUMN IF NOT EXISTS image           text,
+    ADD COLUMN IF NOT EXISTS terminal_type   text,
+    ADD COLUMN IF NOT EXISTS ports           text,
+    ADD COLUMN IF NOT EXISTS cpu_limit       text,
+    ADD COLUMN IF NOT EXISTS memory_limit    text,
+    ADD COLUMN IF NOT EXISTS pids_limit      bigint,
+    ADD COLUMN IF NOT EXISTS env             text,
+    ADD COLUMN IF NOT EXISTS flag            text,
+    ADD COLUMN IF NOT EXISTS record_terminal boolean,
+    ADD COLUMN IF NOT EXISTS status          text,
+    ADD COLUMN IF NOT EXISTS expires_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS template_id     bigint,
+    ADD COLUMN IF NOT EXISTS network_name    text;
+-- Строки, созданные до появления колонок: NULL нельзя прочитать в поля модели без указателей.
+-- Такие лаборатории создавались запущенными, без владельца и с образом из commit_image.
+UPDATE labs SET
+    owner_id        = COALESCE(owner_id, 0),
+    host_port       = COALESCE(host_port, 0),
+    image           = COALESCE(image, commit_image, ''),
+    terminal_type   = COALESCE(terminal_type, ''),
+    cpu_limit       = COALESCE(cpu_limit, ''),
+    memory_limit    = COALESCE(memory_limit, ''),
+    pids_limit      = COALESCE(pids_limit, 0),
+    record_terminal = COALESCE(record_terminal, false),
+    status          = COALESCE(status, 'running'),
+    template_id     = COALESCE(template_id, 0),
+    network_name    = COALESCE(network_name, '');
+CREATE INDEX IF NOT EXISTS idx_labs_status ON labs (status);
+CREATE INDEX IF NOT EXISTS idx_labs_expires_at ON labs (expires_at);
+
+CREATE TABLE IF NOT EXISTS lab_containers (
+    id             bigserial PRIMARY KEY,
+    lab_id         bigint,
+    service_name   text,
+    container_id   text,
+    container_name text,
+    image          text,
+    terminal_type  text,
+    host_port      bigint,
+    access_url     text,
+    ports          text,
+    env            text,
+    depends_on     text,
+    cpu_limit      text,
+    memory_limit   text,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    CONSTRAINT fk_labs_containers FOREIGN KEY (lab_id) REFERENCES labs (id)
+);
+ALTER TABLE lab_containers
+    ADD COLUMN IF NOT EXISTS lab_id         bigint,
+    ADD COLUMN IF NOT EXISTS service_name   text,
+    ADD COLUMN IF NOT EXISTS container_id   text,
+    ADD COLUMN IF NOT EXISTS container_name text,
+    ADD COLUMN IF NOT EXISTS image          text,
+    ADD COLUMN IF NOT EXISTS terminal_type  text,
+    ADD COLUMN IF NOT EXISTS host_port      bigint,
+    ADD COLUMN IF NOT EXISTS access_url     text,
+    ADD COLUMN IF NOT EXISTS ports          text,
+    ADD COLUMN IF NOT EXISTS env            text,
+    ADD COLUMN IF NOT EXISTS depends_on     text,
+    ADD COLUMN IF NOT EXISTS cpu_limit      text,
+    ADD COLUMN IF NOT EXISTS memory_limit   text,
+    ADD COLUMN IF NOT EXISTS created_at     timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at     timestamptz;
+CREATE INDEX IF NOT EXISTS idx_lab_containers_lab_id ON lab_containers (lab_id);
+
+CREATE TABLE IF NOT EXISTS lab_templates (
+    id          bigserial PRIMARY KEY,
+    name        text,
+    description text,
+    services    text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+ALTER TABLE lab_templates
+    ADD COLUMN IF NOT EXISTS name        text,
+    ADD COLUMN IF NOT EXISTS description text,
+    ADD COLUMN IF NOT EXISTS services    text,
+    ADD COLUMN IF NOT EXISTS created_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at  timestamptz;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lab_templates_name ON lab_templates (name);
+
+CREATE TABLE IF NOT EXISTS lab_secrets (
+    id         bigserial PRIMARY KEY,
+    lab_id     bigint,
+    name       text,
+    value      text,
+    created_at timestamptz
+);
+ALTER TABLE lab_secrets
+    ADD COLUMN IF NOT EXISTS lab_id     bigint,
+    ADD COLUMN IF NOT EXISTS name       text,
+    ADD COLUMN IF NOT EXISTS value      text,
+    ADD COLUMN IF NOT EXISTS created_at timestamptz;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lab_secret_name ON lab_secrets (lab_id, name);
+
+CREATE TABLE IF NOT EXISTS lab_check_results (
+    id         bigserial PRIMARY KEY,
+    lab_id     bigint,
+    task_id    bigint,
+    user_id    bigint,
+    score      bigint,
+    max_score  bigint,
+    passed     boolean,
+    checks     text,
+    created_at timestamptz
+);
+ALTER TABLE lab_check_results
+    ADD COLUMN IF NOT EXISTS lab_id     bigint,
+    ADD COLUMN IF NOT EXISTS task_id    bigint,
+    ADD COLUMN IF NOT EXISTS user_id    bigint,
+    ADD COLUMN IF NOT EXISTS score      bigint,
+    ADD COLUMN IF NOT EXISTS max_score  bigint,
+    ADD COLUMN IF NOT EXISTS passed     boolean,
+    ADD COLUMN IF NOT EXISTS checks     text,
+    ADD COLUMN IF NOT EXISTS created_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_lab_check_results_lab_id ON lab_check_results (lab_id);
+
+CREATE TABLE IF NOT EXISTS flag_submissions (
+    id                bigserial PRIMARY KEY,
+    lab_id            bigint,
+    task_id           bigint,
+    user_id           bigint,
+    flag_hash         text,
+    correct           boolean,
+    suspected_sharing boolean,
+    source_lab_id     bigint,
+    source_owner_id   bigint,
+    created_at        timestamptz
+);
+ALTER TABLE flag_submissions
+    ADD COLUMN IF NOT EXISTS lab_id            bigint,
+    ADD COLUMN IF NOT EXISTS task_id           bigint,
+    ADD COLUMN IF NOT EXISTS user_id           bigint,
+    ADD COLUMN IF NOT EXISTS flag_hash         text,
+    ADD COLUMN IF NOT EXISTS correct           boolean,
+    ADD COLUMN IF NOT EXISTS suspected_sharing boolean,
+    ADD COLUMN IF NOT EXISTS source_lab_id     bigint,
+    ADD COLUMN IF NOT EXISTS source_owner_id   bigint,
+    ADD COLUMN IF NOT EXISTS created_at        timestamptz;
+CREATE INDEX IF NOT EXISTS idx_flag_submissions_lab_id ON flag_submissions (lab_id);
+CREATE INDEX IF NOT EXISTS idx_flag_submissions_task_id ON flag_submissions (task_id);
+CREATE INDEX IF NOT EXISTS idx_flag_submissions_suspected_sharing ON flag_submissions (suspected_sharing);
+
+CREATE TABLE IF NOT EXISTS terminal_recordings (
+    id           bigserial PRIMARY KEY,
+    lab_id       bigint,
+    task_id      bigint,
+    user_id      bigint,
+    service_name text,
+    file_path    text,
+    width        bigint,
+    height       bigint,
+    size_bytes   bigint,
+    duration     decimal,
+    started_at   timestamptz,
+    ended_at     timestamptz
+);
+ALTER TABLE terminal_recordings
+    ADD COLUMN IF NOT EXISTS lab_id       bigint,
+    ADD COLUMN IF NOT EXISTS task_id      bigint,
+    ADD COLUMN IF NOT EXISTS user_id      bigint,
+    ADD COLUMN IF NOT EXISTS service_name text,
+    ADD COLUMN IF NOT EXISTS file_path    text,
+    ADD COLUMN IF NOT EXISTS width        bigint,
+    ADD COLUMN IF NOT EXISTS height       bigint,
+    ADD COLUMN IF NOT EXISTS size_bytes   bigint,
+    ADD COLUMN IF NOT EXISTS duration     decimal,
+    ADD COLUMN IF NOT EXISTS started_at   timestamptz,
+    ADD COLUMN IF NOT EXISTS ended_at     timestamptz;
+CREATE INDEX IF NOT EXISTS idx_terminal_recordings_lab_id ON terminal_recordings (lab_id);
+
+CREATE TABLE IF NOT EXISTS exec_audits (
+    id           bigserial PRIMARY KEY,
+    lab_id       bigint,
+    user_id      bigint,
+    container_id text,
+    command      text,
+    exit_code    bigint,
+    duration_ms  bigint,
+    output       text,
+    created_at   timestamptz
+);
+ALTER TABLE exec_audits
+    ADD COLUMN IF NOT EXISTS lab_id       bigint,
+    ADD COLUMN IF NOT EXISTS user_id      bigint,
+    ADD COLUMN IF NOT EXISTS container_id text,
+    ADD COLUMN IF NOT EXISTS command      text,
+    ADD COLUMN IF NOT EXISTS exit_code    bigint,
+    ADD COLUMN IF NOT EXISTS duration_ms  bigint,
+    ADD COLUMN IF NOT EXISTS output       text,
+    ADD COLUMN IF NOT EXISTS created_at   timestamptz;
+CREATE INDEX IF NOT EXISTS idx_exec_audits_lab_id ON exec_audits (lab_id);
+CREATE INDEX IF NOT EXISTS idx_exec_audits_user_id ON exec_audits (user_id);
+CREATE INDEX IF NOT EXISTS idx_exec_audits_created_at ON exec_audits (created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          bigserial PRIMARY KEY,
+    url         text,
+    event_types text,
+    secret      text,
+    active      boolean,
+    description text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN IF NOT EXISTS url         text,
+    ADD COLUMN IF NOT EXISTS event_types text,
+    ADD COLUMN IF NOT EXISTS secret      text,
+    ADD COLUMN IF NOT EXISTS active      boolean,
+    ADD COLUMN IF NOT EXISTS description text,
+    ADD COLUMN IF NOT EXISTS created_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at  timestamptz;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              bigserial PRIMARY KEY,
+    subscription_id bigint,
+    event_id        bigint,
+    event_type      text,
+    payload         text,
+    status          text,
+    attempts        bigint,
+    next_attempt_at timestamptz,
+    redelivery_of   bigint,
+    created_at      timestamptz,
+    updated_at      timestamptz
+);
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS subscription_id bigint,
+    ADD COLUMN IF NOT EXISTS event_id        bigint,
+    ADD COLUMN IF NOT EXISTS event_type      text,
+    ADD COLUMN IF NOT EXISTS payload         text,
+    ADD COLUMN IF NOT EXISTS status          text,
+    ADD COLUMN IF NOT EXISTS attempts        bigint,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz,
+    ADD COLUMN IF NOT EXISTS redelivery_of   bigint,
+    ADD COLUMN IF NOT EXISTS created_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at      timestamptz;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id          bigserial PRIMARY KEY,
+    delivery_id bigint,
+    attempt     bigint,
+    status_code bigint,
+    error       text,
+    duration_ms bigint,
+    created_at  timestamptz,
+    CONSTRAINT fk_webhook_deliveries_attempt_log FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries (id)
+);
+ALTER TABLE webhook_attempts
+    ADD COLUMN IF NOT EXISTS delivery_id bigint,
+    ADD COLUMN IF NOT EXISTS attempt     bigint,
+    ADD COLUMN IF NOT EXISTS status_code bigint,
+    ADD COLUMN IF NOT EXISTS error       text,
+    ADD COLUMN IF NOT EXISTS duration_ms bigint,
+    ADD COLUMN IF NOT EXISTS created_at  timestamptz;
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts (delivery_id);
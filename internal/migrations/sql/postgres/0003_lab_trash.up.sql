@@ -0,0 +1,2 @@
+ALTER TABLE labs ADD COLUMN trashed_at timestamptz;
+CREATE INDEX idx_labs_trashed_at ON labs (trashed_at);
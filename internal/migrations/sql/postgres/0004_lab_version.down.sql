@@ -0,0 +1,2 @@
+ALTER TABLE labs DROP COLUMN IF EXISTS notes;
+ALTER TABLE labs DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- Версия записи для оптимистичной блокировки при частичном обновлении и заметки владельца
+ALTER TABLE labs ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE labs ADD COLUMN notes text NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Операция, которая держит advisory-блокировку лаборатории. Нужна только для ответа
+-- "операция выполняется" на других репликах: саму блокировку обеспечивает pg_advisory_lock.
+CREATE TABLE lab_operations (
+    lab_id       bigint PRIMARY KEY,
+    operation_id text NOT NULL,
+    operation    text NOT NULL,
+    node         text NOT NULL,
+    started_at   timestamptz NOT NULL
+);
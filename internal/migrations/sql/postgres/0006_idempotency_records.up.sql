@@ -0,0 +1,17 @@
+-- Ключи идемпотентности запросов и сохранённые ответы на них
+CREATE TABLE idempotency_records (
+    id              bigserial PRIMARY KEY,
+    user_id         bigint NOT NULL,
+    idempotency_key text NOT NULL,
+    method          text NOT NULL,
+    path            text NOT NULL,
+    request_hash    text NOT NULL,
+    status          text NOT NULL,
+    response_code   bigint NOT NULL DEFAULT 0,
+    response_type   text NOT NULL DEFAULT '',
+    response_body   bytea,
+    created_at      timestamptz NOT NULL,
+    expires_at      timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX idx_idempotency_records_user_key ON idempotency_records (user_id, idempotency_key);
+CREATE INDEX idx_idempotency_records_expires_at ON idempotency_records (expires_at);
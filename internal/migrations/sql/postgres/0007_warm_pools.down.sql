@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_labs_task_status;
+DROP TABLE IF EXISTS warm_pools;
@@ -0,0 +1,10 @@
+-- Настройки тёплых пулов заданий; сами лаборатории пула хранятся в labs со статусом pooled
+CREATE TABLE warm_pools (
+    task_id    bigint PRIMARY KEY,
+    size       bigint NOT NULL,
+    schedule   text,
+    time_zone  text NOT NULL DEFAULT '',
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX idx_labs_task_status ON labs (task_id, status);
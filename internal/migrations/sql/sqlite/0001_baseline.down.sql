@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS exec_audits;
+DROP TABLE IF EXISTS terminal_recordings;
+DROP TABLE IF EXISTS flag_submissions;
+DROP TABLE IF EXISTS lab_check_results;
+DROP TABLE IF EXISTS lab_secrets;
+DROP TABLE IF EXISTS lab_templates;
+DROP TABLE IF EXISTS lab_containers;
+DROP TABLE IF EXISTS labs;
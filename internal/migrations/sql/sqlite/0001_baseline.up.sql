@@ -0,0 +1,171 @@
+-- Та же схема, что и для Postgres, в типах SQLite. Время хранится как datetime,
+-- чтобы драйвер возвращал его как time.Time. SQLite поддерживается только вместе с миграциями,
+-- поэтому баз, созданных AutoMigrate, для неё нет и недостающие колонки не добавляются.
+
+CREATE TABLE IF NOT EXISTS labs (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    title           text,
+    task_id         integer,
+    owner_id        integer,
+    created_at      datetime,
+    updated_at      datetime,
+    container_id    text,
+    container_name  text,
+    access_url      text,
+    host_port       integer,
+    commit_image    text,
+    image           text,
+    terminal_type   text,
+    ports           text,
+    cpu_limit       text,
+    memory_limit    text,
+    pids_limit      integer,
+    env             text,
+    flag            text,
+    record_terminal boolean,
+    status          text,
+    expires_at      datetime,
+    template_id     integer,
+    network_name    text
+);
+CREATE INDEX IF NOT EXISTS idx_labs_status ON labs (status);
+CREATE INDEX IF NOT EXISTS idx_labs_expires_at ON labs (expires_at);
+
+CREATE TABLE IF NOT EXISTS lab_containers (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    lab_id         integer,
+    service_name   text,
+    container_id   text,
+    container_name text,
+    image          text,
+    terminal_type  text,
+    host_port      integer,
+    access_url     text,
+    ports          text,
+    env            text,
+    depends_on     text,
+    cpu_limit      text,
+    memory_limit   text,
+    created_at     datetime,
+    updated_at     datetime,
+    CONSTRAINT fk_labs_containers FOREIGN KEY (lab_id) REFERENCES labs (id)
+);
+CREATE INDEX IF NOT EXISTS idx_lab_containers_lab_id ON lab_containers (lab_id);
+
+CREATE TABLE IF NOT EXISTS lab_templates (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    name        text,
+    description text,
+    services    text,
+    created_at  datetime,
+    updated_at  datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lab_templates_name ON lab_templates (name);
+
+CREATE TABLE IF NOT EXISTS lab_secrets (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    lab_id     integer,
+    name       text,
+    value      text,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lab_secret_name ON lab_secrets (lab_id, name);
+
+CREATE TABLE IF NOT EXISTS lab_check_results (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    lab_id     integer,
+    task_id    integer,
+    user_id    integer,
+    score      integer,
+    max_score  integer,
+    passed     boolean,
+    checks     text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_lab_check_results_lab_id ON lab_check_results (lab_id);
+
+CREATE TABLE IF NOT EXISTS flag_submissions (
+    id                integer PRIMARY KEY AUTOINCREMENT,
+    lab_id            integer,
+    task_id           integer,
+    user_id           integer,
+    flag_hash         text,
+    correct           boolean,
+    suspected_sharing boolean,
+    source_lab_id     integer,
+    source_owner_id   integer,
+    created_at        datetime
+);
+CREATE INDEX IF NOT EXISTS idx_flag_submissions_lab_id ON flag_submissions (lab_id);
+CREATE INDEX IF NOT EXISTS idx_flag_submissions_task_id ON flag_submissions (task_id);
+CREATE INDEX IF NOT EXISTS idx_flag_submissions_suspected_sharing ON flag_submissions (suspected_sharing);
+
+CREATE TABLE IF NOT EXISTS terminal_recordings (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    lab_id       integer,
+    task_id      integer,
+    user_id      integer,
+    service_name text,
+    file_path    text,
+    width        integer,
+    height       integer,
+    size_bytes   integer,
+    duration     real,
+    started_at   datetime,
+    ended_at     datetime
+);
+CREATE INDEX IF NOT EXISTS idx_terminal_recordings_lab_id ON terminal_recordings (lab_id);
+
+CREATE TABLE IF NOT EXISTS exec_audits (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    lab_id       integer,
+    user_id      integer,
+    container_id text,
+    command      text,
+    exit_code    integer,
+    duration_ms  integer,
+    output       text,
+    created_at   datetime
+);
+CREATE INDEX IF NOT EXISTS idx_exec_audits_lab_id ON exec_audits (lab_id);
+CREATE INDEX IF NOT EXISTS idx_exec_audits_user_id ON exec_audits (user_id);
+CREATE INDEX IF NOT EXISTS idx_exec_audits_created_at ON exec_audits (created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    url         text,
+    event_types text,
+    secret      text,
+    active      boolean,
+    description text,
+    created_at  datetime,
+    updated_at  datetime
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    subscription_id integer,
+    event_id        integer,
+    event_type      text,
+    payload         text,
+    status          text,
+    attempts        integer,
+    next_attempt_at datetime,
+    redelivery_of   integer,
+    created_at      datetime,
+    updated_at      datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    delivery_id integer,
+    attempt     integer,
+    status_code integer,
+    error       text,
+    duration_ms integer,
+    created_at  datetime,
+    CONSTRAINT fk_webhook_deliveries_attempt_log FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts (delivery_id);
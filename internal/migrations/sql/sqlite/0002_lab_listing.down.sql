@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_labs_task_id_created_at;
+DROP INDEX IF EXISTS idx_labs_owner_id_created_at;
+DROP INDEX IF EXISTS idx_labs_updated_at_id;
+DROP INDEX IF EXISTS idx_labs_created_at_id;
+
+ALTER TABLE labs DROP COLUMN labels;
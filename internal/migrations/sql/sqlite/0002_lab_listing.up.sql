@@ -0,0 +1,8 @@
+ALTER TABLE labs ADD COLUMN labels text;
+
+-- Индексы под постраничный список лабораторий: сортировка по времени с ID как вторым ключом.
+-- Фильтр по меткам обходится без индекса: для локальной базы хватает полного просмотра.
+CREATE INDEX idx_labs_created_at_id ON labs (created_at, id);
+CREATE INDEX idx_labs_updated_at_id ON labs (updated_at, id);
+CREATE INDEX idx_labs_owner_id_created_at ON labs (owner_id, created_at, id);
+CREATE INDEX idx_labs_task_id_created_at ON labs (task_id, created_at, id);
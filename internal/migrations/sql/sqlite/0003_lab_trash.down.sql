@@ -0,0 +1,4 @@
+-- Лаборатории из корзины без отметки времени не отличить от обычных, поэтому возвращаем их в stopped
+UPDATE labs SET status = 'stopped' WHERE status = 'trashed';
+DROP INDEX IF EXISTS idx_labs_trashed_at;
+ALTER TABLE labs DROP COLUMN trashed_at;
@@ -0,0 +1,2 @@
+ALTER TABLE labs DROP COLUMN notes;
+ALTER TABLE labs DROP COLUMN version;
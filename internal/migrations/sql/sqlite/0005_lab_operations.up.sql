@@ -0,0 +1,8 @@
+-- Для SQLite используются блокировки внутри процесса; таблица создаётся, чтобы версии схемы совпадали с Postgres
+CREATE TABLE lab_operations (
+    lab_id       integer PRIMARY KEY,
+    operation_id text NOT NULL,
+    operation    text NOT NULL,
+    node         text NOT NULL,
+    started_at   datetime NOT NULL
+);
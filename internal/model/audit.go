@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ExecAudit — запись журнала команд, выполненных в контейнерах лабораторий
+type ExecAudit struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	LabID       uint      `gorm:"index" json:"lab_id"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	ContainerID string    `json:"container_id"`
+	Command     string    `json:"command"`
+	ExitCode    int       `json:"exit_code"` // -1, если команду не удалось запустить
+	DurationMs  int64     `json:"duration_ms"`
+	Output      string    `json:"output"` // Обрезается до maxAuditOutput байт
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// ExecAuditFilter — условия выборки из журнала команд. Нулевые поля не фильтруют.
+type ExecAuditFilter struct {
+	LabID   uint
+	UserID  uint
+	From    time.Time
+	To      time.Time
+	Command string // Подстрока команды без учёта регистра
+	Limit   int
+	Offset  int
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// LabCheckResult — одна попытка автоматической проверки лаборатории
+type LabCheckResult struct {
+	ID        uint           `gorm:"primary_key" json:"id"`
+	LabID     uint           `gorm:"index" json:"lab_id"`
+	TaskID    uint           `json:"task_id"`
+	UserID    uint           `json:"user_id"` // Пользователь, запустивший проверку
+	Score     int            `json:"score"`
+	MaxScore  int            `json:"max_score"`
+	Passed    bool           `json:"passed"` // Все проверки пройдены
+	Checks    []CheckOutcome `gorm:"serializer:json" json:"checks"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// CheckOutcome — результат одного скрипта проверки
+type CheckOutcome struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+	Points   int    `json:"points"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Типы событий жизненного цикла лаборатории
+const (
+	EventLabCreated   = "lab.created"
+	EventLabStarted   = "lab.started"
+	EventLabStopped   = "lab.stopped"
+	EventLabCommitted = "lab.committed"
+	EventLabDeleted   = "lab.deleted"
+	EventLabFailed    = "lab.failed"
+	EventLabExpired   = "lab.expired"
+	EventLabTrashed   = "lab.trashed"  // Лаборатория удалена в корзину
+	EventLabRestored  = "lab.restored" // Лаборатория восстановлена из корзины
+	EventLabUpdated   = "lab.updated"  // Изменены название, метки, заметки или срок жизни
+)
+
+// LabEventTypes — все типы событий лаборатории
+var LabEventTypes = []string{
+	EventLabCreated, EventLabStarted, EventLabStopped, EventLabCommitted,
+	EventLabDeleted, EventLabFailed, EventLabExpired, EventLabTrashed, EventLabRestored,
+	EventLabUpdated,
+}
+
+// LabEvent — событие жизненного цикла лаборатории.
+// ID монотонно растёт и служит курсором для возобновления подписки.
+type LabEvent struct {
+	ID      uint64         `json:"id"`
+	Type    string         `json:"type"`
+	LabID   uint           `json:"lab_id"`
+	OwnerID uint           `json:"owner_id"`
+	TaskID  uint           `json:"task_id"`
+	Time    time.Time      `json:"time"`
+	Data    map[string]any `json:"data,omitempty"`
+}
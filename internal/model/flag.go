@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// FlagSubmission — попытка сдать флаг из лаборатории
+type FlagSubmission struct {
+	ID               uint      `gorm:"primary_key" json:"id"`
+	LabID            uint      `gorm:"index" json:"lab_id"`
+	TaskID           uint      `gorm:"index" json:"task_id"`
+	UserID           uint      `json:"user_id"`
+	FlagHash         string    `json:"-"` // SHA-256 отправленного значения, сам флаг не хранится
+	Correct          bool      `json:"correct"`
+	SuspectedSharing bool      `gorm:"index" json:"suspected_sharing"`
+	SourceLabID      uint      `json:"source_lab_id,omitempty"`   // Лаборатория, которой на самом деле принадлежит флаг
+	SourceOwnerID    uint      `json:"source_owner_id,omitempty"` // Владелец этой лаборатории
+	CreatedAt        time.Time `json:"created_at"`
+}
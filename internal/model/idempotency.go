@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Состояния запроса с ключом идемпотентности
+const (
+	IdempotencyPending   = "pending"   // Запрос выполняется
+	IdempotencyCompleted = "completed" // Ответ сохранён и отдаётся при повторе
+)
+
+// IdempotencyRecord — запрос с заголовком Idempotency-Key и сохранённый ответ на него.
+// Ключ уникален в пределах пользователя.
+type IdempotencyRecord struct {
+	ID             uint   `gorm:"primary_key"`
+	UserID         uint   `gorm:"uniqueIndex:idx_idempotency_records_user_key"`
+	IdempotencyKey string `gorm:"uniqueIndex:idx_idempotency_records_user_key"`
+	Method         string
+	Path           string
+	RequestHash    string // sha256 метода, пути и тела запроса
+	Status         string
+	ResponseCode   int
+	ResponseType   string
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time `gorm:"index"`
+}
@@ -2,14 +2,116 @@ package model
 
 import "time"
 
+// Состояния лаборатории
+const (
+	LabStatusRunning = "running"
+	LabStatusStopped = "stopped"
+	LabStatusFailed  = "failed"  // Последний запуск завершился ошибкой
+	LabStatusExpired = "expired" // Истёк срок жизни, контейнеры остановлены
+	LabStatusTrashed = "trashed" // Удалена в корзину, восстанавливается до окончательного удаления
+	LabStatusPooled  = "pooled"  // Создана заранее в тёплом пуле и ещё не выдана пользователю
+)
+
 type Lab struct {
-	ID            uint      `gorm:"primary_key" json:"id"`
-	Title         string    `json:"title"`        // Название лаборатории
-	TaskID        uint      `json:"task_id"`      // Ссылка на задание (task_id из сервиса заданий)
-	CreatedAt     time.Time `json:"created_at"`   // Время создания лаборатории
-	UpdatedAt     time.Time `json:"updated_at"`   // Время последнего обновления лаборатории
-	ContainerID   string    `json:"container_id"` // ID Docker контейнера
-	ContainerName string    `json:"container_name"`
-	AccessURL     string    `json:"access_url"`
-	CommitImage   string    `json:"commit_image"`
+	ID    uint   `gorm:"primary_key" json:"id"`
+	Title string `json:"title"` // Название лаборатории
+	Notes string `json:"notes"` // Заметки владельца
+	// Произвольные метки для поиска и группировки, например course=linux-101
+	Labels        map[string]string `gorm:"serializer:json;type:jsonb" json:"labels,omitempty"`
+	TaskID        uint              `json:"task_id"`      // Ссылка на задание (task_id из сервиса заданий)
+	OwnerID       uint              `json:"owner_id"`     // Пользователь, создавший лабораторию
+	CreatedAt     time.Time         `json:"created_at"`   // Время создания лаборатории
+	UpdatedAt     time.Time         `json:"updated_at"`   // Время последнего обновления лаборатории
+	ContainerID   string            `json:"container_id"` // ID Docker контейнера
+	ContainerName string            `json:"container_name"`
+	AccessURL     string            `json:"access_url"`
+	HostPort      int               `json:"host_port"` // Порт хоста, на который опубликован терминал
+	CommitImage   string            `json:"commit_image"`
+	Image         string            `json:"image"`                        // Исходный образ задания
+	TerminalType  string            `json:"terminal_type"`                // Тип терминала в контейнере
+	Ports         []int             `gorm:"serializer:json" json:"ports"` // Дополнительные опубликованные порты
+	CPULimit      string            `json:"cpu_limit"`
+	MemoryLimit   string            `json:"memory_limit"`
+	PidsLimit     int               `json:"pids_limit"`
+	// Шаблоны переменных окружения; значения подставляются при каждом запуске контейнера
+	Env  map[string]string `gorm:"serializer:json" json:"-"`
+	Flag *FlagDefinition   `gorm:"serializer:json" json:"-"` // Параметры флага CTF, сам флаг вычисляется из ID лаборатории
+
+	RecordTerminal bool `json:"record_terminal"` // Записываются ли терминальные сессии через сервис
+
+	Status    string     `gorm:"index" json:"status"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"`           // После этого времени лаборатория останавливается, пусто — бессрочно
+	TrashedAt *time.Time `gorm:"index" json:"trashed_at,omitempty"` // Время удаления в корзину
+
+	// Для лабораторий из нескольких контейнеров поля ContainerID, ContainerName и AccessURL
+	// указывают на основной сервис, а все сервисы перечислены в Containers
+	TemplateID  uint           `json:"template_id,omitempty"`
+	NetworkName string         `json:"network_name,omitempty"`
+	Containers  []LabContainer `gorm:"foreignKey:LabID" json:"containers,omitempty"`
+
+	// Растёт при каждом изменении записи; используется как ETag для оптимистичной блокировки
+	Version uint `json:"version"`
+}
+
+// LabContainer — контейнер одного сервиса в лаборатории из нескольких контейнеров
+type LabContainer struct {
+	ID            uint              `gorm:"primary_key" json:"id"`
+	LabID         uint              `gorm:"index" json:"lab_id"`
+	ServiceName   string            `json:"service_name"`
+	ContainerID   string            `json:"container_id"`
+	ContainerName string            `json:"container_name"`
+	Image         string            `json:"image"`
+	TerminalType  string            `json:"terminal_type"`
+	HostPort      int               `json:"host_port"`
+	AccessURL     string            `json:"access_url"`
+	Ports         []int             `gorm:"serializer:json" json:"ports"`
+	Env           map[string]string `gorm:"serializer:json" json:"-"`
+	DependsOn     []string          `gorm:"serializer:json" json:"depends_on"`
+	CPULimit      string            `json:"cpu_limit"`
+	MemoryLimit   string            `json:"memory_limit"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// LabPatch — изменения лаборатории от пользователя. Пустые указатели не меняют поле.
+type LabPatch struct {
+	Title  *string
+	Labels *map[string]string
+	Notes  *string
+	// Новый срок жизни от текущего момента в минутах, 0 — бессрочно
+	TTLMinutes *int
+}
+
+// Поля сортировки списка лабораторий
+const (
+	LabSortCreatedAt = "created_at"
+	LabSortUpdatedAt = "updated_at"
+)
+
+// LabFilter — условия выборки списка лабораторий. Нулевые поля не фильтруют.
+type LabFilter struct {
+	TaskID      uint
+	OwnerID     uint
+	Status      string // Пусто — все лаборатории, кроме удалённых в корзину
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Labels      map[string]string // Метки с точным значением
+	LabelKeys   []string          // Метки, которые должны быть заданы с любым значением
+	SortBy      string            // created_at (по умолчанию) или updated_at
+	Desc        bool
+	After       *LabCursor // Позиция, после которой начинается страница
+	Limit       int
+}
+
+// LabCursor — позиция в списке: значение поля сортировки и ID последней лаборатории страницы
+type LabCursor struct {
+	Time time.Time
+	ID   uint
+}
+
+// LabCount — число лабораторий задания в одном состоянии
+type LabCount struct {
+	TaskID uint
+	Status string
+	Count  int64
 }
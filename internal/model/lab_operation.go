@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// LabOperation — операция жизненного цикла, которая держит блокировку лаборатории
+type LabOperation struct {
+	ID        string    `json:"id"`
+	LabID     uint      `json:"lab_id"`
+	Operation string    `json:"operation"`      // start, stop, commit, delete, restore, purge, update, expire
+	Node      string    `json:"node,omitempty"` // Хост реплики, выполняющей операцию
+	StartedAt time.Time `json:"started_at"`
+}
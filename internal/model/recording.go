@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// TerminalRecording — запись терминальной сессии в формате asciinema v2
+type TerminalRecording struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	LabID       uint       `gorm:"index" json:"lab_id"`
+	TaskID      uint       `json:"task_id"`
+	UserID      uint       `json:"user_id"`
+	ServiceName string     `json:"service_name,omitempty"`
+	FilePath    string     `json:"-"` // Путь к .cast файлу на диске сервиса
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Duration    float64    `json:"duration"` // Длительность в секундах
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at"` // Пусто, пока сессия идёт
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// LabSecret — сгенерированный для лаборатории секрет. Значение хранится только в зашифрованном виде.
+type LabSecret struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	LabID     uint      `gorm:"uniqueIndex:idx_lab_secret_name" json:"lab_id"`
+	Name      string    `gorm:"uniqueIndex:idx_lab_secret_name" json:"name"`
+	Value     string    `json:"-"` // Зашифрованное значение
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,119 @@
+package model
+
+// Типы терминалов, которые поднимаются внутри контейнера лаборатории
+const (
+	TerminalTTYD  = "ttyd"
+	TerminalWetty = "wetty"
+)
+
+// TaskDefinition описывает задание так, как его отдаёт сервис заданий.
+// Эта же структура используется администратором для переопределения параметров при создании лаборатории.
+type TaskDefinition struct {
+	ID           uint   `json:"id"`
+	Title        string `json:"title"`
+	VMImagePath  string `json:"vm_image_path"` // Docker-образ задания
+	TerminalType string `json:"terminal_type"` // ttyd или wetty
+	Ports        []int  `json:"ports"`         // Дополнительные порты контейнера для публикации
+	CPULimit     string `json:"cpu_limit"`     // Значение для docker run --cpus
+	MemoryLimit  string `json:"memory_limit"`  // Значение для docker run --memory
+	PidsLimit    int    `json:"pids_limit"`    // Значение для docker run --pids-limit
+
+	TemplateID uint `json:"template_id"` // Шаблон из нескольких контейнеров вместо одного образа
+
+	// Значения переменных окружения могут содержать шаблоны {{lab.id}}, {{user.id}}, {{task.id}} и {{secret.NAME}}
+	Env     map[string]string  `json:"env"`
+	Secrets []SecretDefinition `json:"secrets"` // Секреты, которые генерируются для каждой лаборатории
+
+	Flag *FlagDefinition `json:"flag"` // Уникальный флаг лаборатории для CTF-заданий
+
+	RecordTerminal bool `json:"record_terminal"` // Записывать терминальные сессии в формате asciinema; ttyd/wetty при этом не публикуется
+	TTLMinutes     int  `json:"ttl_minutes"`     // Срок жизни лаборатории, 0 — бессрочно
+
+	Checks []TaskCheck `json:"checks"` // Скрипты автоматической проверки выполнения
+}
+
+// SecretDefinition описывает секрет, генерируемый для каждой лаборатории.
+// Секрет передаётся в основной контейнер переменной окружения с именем Name.
+type SecretDefinition struct {
+	Name   string `json:"name"`
+	Length int    `json:"length"` // По умолчанию 32 символа
+	Format string `json:"format"` // hex (по умолчанию) или alnum
+}
+
+// FlagDefinition описывает, как флаг лаборатории выглядит и как попадает в контейнер.
+// Если не указаны ни Env, ни File, флаг передаётся в переменной окружения FLAG.
+type FlagDefinition struct {
+	Prefix string `json:"prefix"` // Флаг имеет вид prefix{...}, по умолчанию flag
+	Env    string `json:"env"`    // Переменная окружения основного контейнера
+	File   string `json:"file"`   // Абсолютный путь к файлу в основном контейнере
+}
+
+// TaskCheck — один скрипт проверки лаборатории.
+// Проверка пройдена, если код завершения и вывод совпали с ожидаемыми.
+type TaskCheck struct {
+	Name             string `json:"name"`
+	Service          string `json:"service"`            // Сервис шаблона, в котором выполняется скрипт
+	Script           string `json:"script"`             // Выполняется через sh -c внутри контейнера
+	ExpectedExitCode *int   `json:"expected_exit_code"` // По умолчанию 0
+	ExpectedOutput   string `json:"expected_output"`    // Регулярное выражение для вывода, необязательно
+	Points           int    `json:"points"`             // По умолчанию 1
+	TimeoutSeconds   int    `json:"timeout_seconds"`    // По умолчанию 30
+}
+
+// ApplyOverride переносит в определение задания все непустые поля из override
+func (t *TaskDefinition) ApplyOverride(override *TaskDefinition) {
+	if override == nil {
+		return
+	}
+	if override.VMImagePath != "" {
+		t.VMImagePath = override.VMImagePath
+	}
+	if override.TerminalType != "" {
+		t.TerminalType = override.TerminalType
+	}
+	if override.Ports != nil {
+		t.Ports = override.Ports
+	}
+	if override.CPULimit != "" {
+		t.CPULimit = override.CPULimit
+	}
+	if override.MemoryLimit != "" {
+		t.MemoryLimit = override.MemoryLimit
+	}
+	if override.PidsLimit != 0 {
+		t.PidsLimit = override.PidsLimit
+	}
+	if override.TemplateID != 0 {
+		t.TemplateID = override.TemplateID
+	}
+	if len(override.Env) > 0 {
+		env := make(map[string]string, len(t.Env)+len(override.Env))
+		for key, value := range t.Env {
+			env[key] = value
+		}
+		for key, value := range override.Env {
+			env[key] = value
+		}
+		t.Env = env
+	}
+	if override.Secrets != nil {
+		t.Secrets = override.Secrets
+	}
+	if override.Flag != nil {
+		t.Flag = override.Flag
+	}
+	if override.RecordTerminal {
+		t.RecordTerminal = true
+	}
+	if override.TTLMinutes != 0 {
+		t.TTLMinutes = override.TTLMinutes
+	}
+}
+
+// TerminalPort возвращает порт, который слушает терминал внутри контейнера
+func TerminalPort(terminalType string) int {
+	if terminalType == TerminalWetty {
+		return 3000
+	}
+	return 7681
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// LabTemplate описывает лабораторию из нескольких контейнеров в общей приватной сети
+type LabTemplate struct {
+	ID          uint              `gorm:"primary_key" json:"id"`
+	Name        string            `gorm:"uniqueIndex" json:"name"`
+	Description string            `json:"description"`
+	Services    []TemplateService `gorm:"serializer:json" json:"services"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// TemplateService — один именованный контейнер шаблона
+type TemplateService struct {
+	Name         string            `json:"name"` // Имя сервиса, оно же DNS-имя в сети лаборатории
+	Image        string            `json:"image"`
+	TerminalType string            `json:"terminal_type"` // Пусто, если терминал сервису не нужен
+	Ports        []int             `json:"ports"`
+	Env          map[string]string `json:"env"`
+	DependsOn    []string          `json:"depends_on"` // Сервисы, которые должны быть запущены раньше
+	CPULimit     string            `json:"cpu_limit"`
+	MemoryLimit  string            `json:"memory_limit"`
+}
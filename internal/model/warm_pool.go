@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// WarmPool — настройки тёплого пула задания: сколько заранее созданных лабораторий
+// держать готовыми, чтобы CreateLab выдавал их сразу, без запуска контейнера
+type WarmPool struct {
+	TaskID    uint         `gorm:"primaryKey;autoIncrement:false" json:"task_id"`
+	Size      int          `json:"size"`                            // Размер пула в часы расписания
+	Schedule  []PoolWindow `gorm:"serializer:json" json:"schedule"` // Пусто — пул заполнен круглосуточно
+	TimeZone  string       `json:"time_zone"`                       // Часовой пояс расписания, по умолчанию UTC
+	Ready     int          `gorm:"-" json:"ready"`                  // Сколько лабораторий в пуле сейчас
+	Target    int          `gorm:"-" json:"target"`                 // Размер пула по расписанию в текущий момент
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// PoolWindow — интервал времени, в который пул заполняется до Size.
+// Вне всех интервалов размер пула нулевой.
+type PoolWindow struct {
+	Days  []string `json:"days"`  // mon, tue, wed, thu, fri, sat, sun; пусто — каждый день
+	Start string   `json:"start"` // ЧЧ:ММ
+	End   string   `json:"end"`   // ЧЧ:ММ, не включая; раньше Start — интервал через полночь
+}
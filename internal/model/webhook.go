@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Состояния доставки вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" // Исчерпаны все попытки
+)
+
+// WebhookSubscription — подписка внешней системы на события лабораторий
+type WebhookSubscription struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `gorm:"serializer:json" json:"event_types"` // Пустой список — все события
+	Secret      string    `json:"secret,omitempty"`                   // Ключ подписи, хранится зашифрованным и не возвращается
+	Active      bool      `json:"active"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Matches сообщает, подписан ли вебхук на событие указанного типа
+func (w *WebhookSubscription) Matches(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery — доставка одного события одной подписке
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primary_key" json:"id"`
+	SubscriptionID uint       `gorm:"index" json:"subscription_id"`
+	EventID        uint64     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"` // Тело запроса, JSON события
+	Status         string     `gorm:"index" json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	RedeliveryOf   uint       `json:"redelivery_of,omitempty"` // Исходная доставка для повторной отправки вручную
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	AttemptLog []WebhookAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+}
+
+// WebhookAttempt — одна попытка HTTP-запроса доставки
+type WebhookAttempt struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	DeliveryID uint      `gorm:"index" json:"delivery_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"` // 0, если ответ не получен
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,631 @@
+// Package conformance содержит общий набор проверок хранилища лабораторий.
+// Каждая реализация interfaces.LabInterface (Postgres, SQLite) должна проходить его
+// одинаково: так локальная база ведёт себя так же, как рабочая.
+//
+// Проверки создают лаборатории со случайными task_id и owner_id и удаляют их в конце,
+// поэтому набор можно запускать и на базе с данными.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// Case — одна проверка набора
+type Case struct {
+	Name string
+	Run  func(ctx context.Context, f *fixture) error
+}
+
+// Cases — все проверки в порядке выполнения
+var Cases = []Case{
+	{"create and get", createAndGet},
+	{"update", update},
+	{"update fields", updateFields},
+	{"get labs by task", getLabsByTask},
+	{"list filters", listFilters},
+	{"list labels", listLabels},
+	{"list paging", listPaging},
+	{"list time zones", listTimeZones},
+	{"expired labs", expiredLabs},
+	{"trashed labs", trashedLabs},
+	{"count labs", countLabs},
+	{"delete", deleteLab},
+}
+
+// Result — итог одной проверки; Err пуст, если проверка пройдена
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Run выполняет все проверки на хранилище repo и возвращает результат каждой.
+// Проверки независимы: ошибка одной не останавливает остальные.
+func Run(ctx context.Context, repo interfaces.LabInterface) []Result {
+	results := make([]Result, 0, len(Cases))
+	for _, c := range Cases {
+		results = append(results, Result{Name: c.Name, Err: RunCase(ctx, repo, c)})
+	}
+	return results
+}
+
+// RunCase выполняет одну проверку на хранилище repo и удаляет созданные ею лаборатории
+func RunCase(ctx context.Context, repo interfaces.LabInterface, c Case) error {
+	f := newFixture(repo)
+	err := c.Run(ctx, f)
+	if cleanupErr := f.cleanup(ctx); cleanupErr != nil {
+		err = errors.Join(err, fmt.Errorf("cleanup: %w", cleanupErr))
+	}
+	return err
+}
+
+// fixture — лаборатории одной проверки. Свои task_id и owner_id отделяют их от остальных данных базы.
+type fixture struct {
+	repo    interfaces.LabInterface
+	taskID  uint
+	ownerID uint
+	created []uint
+}
+
+func newFixture(repo interfaces.LabInterface) *fixture {
+	return &fixture{
+		repo:    repo,
+		taskID:  uint(1_000_000_000 + rand.IntN(1_000_000_000)),
+		ownerID: uint(1_000_000_000 + rand.IntN(1_000_000_000)),
+	}
+}
+
+func (f *fixture) create(ctx context.Context, lab *model.Lab) (*model.Lab, error) {
+	if lab.TaskID == 0 {
+		lab.TaskID = f.taskID
+	}
+	if lab.OwnerID == 0 {
+		lab.OwnerID = f.ownerID
+	}
+	if lab.Status == "" {
+		lab.Status = model.LabStatusRunning
+	}
+	if err := f.repo.CreateLab(ctx, lab); err != nil {
+		return nil, fmt.Errorf("create lab: %w", err)
+	}
+	if lab.ID == 0 {
+		return nil, errors.New("create lab: ID is not assigned")
+	}
+	f.created = append(f.created, lab.ID)
+	return lab, nil
+}
+
+func (f *fixture) cleanup(ctx context.Context) error {
+	var errs []error
+	for _, id := range f.created {
+		if err := f.repo.DeleteLab(ctx, int(id)); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Время в базе хранится с точностью до микросекунд
+func sameTime(a, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
+
+func ids(labs []*model.Lab) []uint {
+	result := make([]uint, 0, len(labs))
+	for _, lab := range labs {
+		result = append(result, lab.ID)
+	}
+	return result
+}
+
+// Сравнивает ID с учётом порядка
+func expectIDs(what string, labs []*model.Lab, want ...uint) error {
+	got := ids(labs)
+	if !slices.Equal(got, want) {
+		return fmt.Errorf("%s: got labs %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+func createAndGet(ctx context.Context, f *fixture) error {
+	expires := time.Now().Add(time.Hour)
+	lab, err := f.create(ctx, &model.Lab{
+		Title:          "conformance",
+		Labels:         map[string]string{"course": "linux-101"},
+		ContainerID:    "abc123",
+		Ports:          []int{8080, 9090},
+		Env:            map[string]string{"USER": "student"},
+		Flag:           &model.FlagDefinition{},
+		RecordTerminal: true,
+		ExpiresAt:      &expires,
+		Containers: []model.LabContainer{
+			{ServiceName: "web", DependsOn: []string{"db"}},
+			{ServiceName: "db"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	got, err := f.repo.GetLab(ctx, int(lab.ID))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	switch {
+	case got.Title != lab.Title || got.ContainerID != lab.ContainerID || got.TaskID != f.taskID || got.OwnerID != f.ownerID:
+		return fmt.Errorf("get lab: scalar fields differ: %+v", got)
+	case got.Labels["course"] != "linux-101" || len(got.Labels) != 1:
+		return fmt.Errorf("get lab: labels = %v", got.Labels)
+	case !slices.Equal(got.Ports, lab.Ports) || got.Env["USER"] != "student" || got.Flag == nil:
+		return fmt.Errorf("get lab: serialized fields differ: ports %v, env %v", got.Ports, got.Env)
+	case !got.RecordTerminal || got.Status != model.LabStatusRunning:
+		return fmt.Errorf("get lab: record_terminal %v, status %q", got.RecordTerminal, got.Status)
+	case got.ExpiresAt == nil || !sameTime(*got.ExpiresAt, expires):
+		return fmt.Errorf("get lab: expires_at = %v, want %v", got.ExpiresAt, expires)
+	case got.TrashedAt != nil:
+		return fmt.Errorf("get lab: trashed_at = %v, want empty", got.TrashedAt)
+	case got.CreatedAt.IsZero() || got.UpdatedAt.IsZero():
+		return errors.New("get lab: timestamps are not set")
+	case got.Version != 1:
+		return fmt.Errorf("get lab: version = %d, want 1", got.Version)
+	}
+	if len(got.Containers) != 2 || got.Containers[0].ServiceName != "web" || got.Containers[1].ServiceName != "db" {
+		return fmt.Errorf("get lab: containers must be loaded in creation order, got %+v", got.Containers)
+	}
+	if !slices.Equal(got.Containers[0].DependsOn, []string{"db"}) || got.Containers[0].LabID != lab.ID {
+		return fmt.Errorf("get lab: container fields differ: %+v", got.Containers[0])
+	}
+
+	if _, err := f.repo.GetLab(ctx, int(lab.ID)+1_000_000_000); !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("get missing lab: error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	return nil
+}
+
+func update(ctx context.Context, f *fixture) error {
+	lab, err := f.create(ctx, &model.Lab{Title: "before", Containers: []model.LabContainer{{ServiceName: "web"}}})
+	if err != nil {
+		return err
+	}
+	lab, err = f.repo.GetLab(ctx, int(lab.ID))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	lab.Title = "after"
+	lab.Labels = map[string]string{"team": "blue"}
+	lab.Status = model.LabStatusStopped
+	lab.ExpiresAt = nil
+	if err := f.repo.UpdateLab(ctx, lab); err != nil {
+		return fmt.Errorf("update lab: %w", err)
+	}
+	container := lab.Containers[0]
+	container.ContainerID = "def456"
+	if err := f.repo.UpdateLabContainer(ctx, &container); err != nil {
+		return fmt.Errorf("update lab container: %w", err)
+	}
+
+	got, err := f.repo.GetLab(ctx, int(lab.ID))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	if got.Title != "after" || got.Status != model.LabStatusStopped || got.Labels["team"] != "blue" || got.ExpiresAt != nil {
+		return fmt.Errorf("updated lab differs: %+v", got)
+	}
+	if len(got.Containers) != 1 || got.Containers[0].ContainerID != "def456" {
+		return fmt.Errorf("updated container differs: %+v", got.Containers)
+	}
+	if got.UpdatedAt.Before(got.CreatedAt) {
+		return fmt.Errorf("updated_at %v is before created_at %v", got.UpdatedAt, got.CreatedAt)
+	}
+	if got.Version != 2 {
+		return fmt.Errorf("version after update = %d, want 2", got.Version)
+	}
+	return nil
+}
+
+// Частичное обновление меняет только указанные колонки и только при совпадении версии
+func updateFields(ctx context.Context, f *fixture) error {
+	expires := time.Now().Add(time.Hour)
+	lab, err := f.create(ctx, &model.Lab{Title: "before", ContainerID: "abc123", ExpiresAt: &expires, Containers: []model.LabContainer{{ServiceName: "web"}}})
+	if err != nil {
+		return err
+	}
+	createdUpdatedAt := lab.UpdatedAt
+
+	patch := &model.Lab{
+		ID:          lab.ID,
+		Title:       "after",
+		Notes:       "remember to commit",
+		Labels:      map[string]string{"team": "red"},
+		ContainerID: "must not be written",
+	}
+	updated, err := f.repo.UpdateLabFields(ctx, patch, 1, "title", "notes", "labels", "expires_at")
+	if err != nil {
+		return fmt.Errorf("update fields: %w", err)
+	}
+	if !updated || patch.Version != 2 {
+		return fmt.Errorf("update fields with current version: updated %v, version %d", updated, patch.Version)
+	}
+
+	got, err := f.repo.GetLab(ctx, int(lab.ID))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	switch {
+	case got.Title != "after" || got.Notes != "remember to commit" || got.Labels["team"] != "red":
+		return fmt.Errorf("selected fields are not updated: %+v", got)
+	case got.ExpiresAt != nil:
+		return fmt.Errorf("expires_at = %v, want empty", got.ExpiresAt)
+	case got.ContainerID != "abc123" || got.Status != model.LabStatusRunning || len(got.Containers) != 1:
+		return fmt.Errorf("fields outside the selection changed: %+v", got)
+	case got.Version != 2:
+		return fmt.Errorf("version = %d, want 2", got.Version)
+	case got.UpdatedAt.Before(createdUpdatedAt):
+		return fmt.Errorf("updated_at %v is before %v", got.UpdatedAt, createdUpdatedAt)
+	}
+
+	stale := &model.Lab{ID: lab.ID, Title: "stale"}
+	if updated, err := f.repo.UpdateLabFields(ctx, stale, 1, "title"); err != nil || updated {
+		return fmt.Errorf("update fields with stale version: updated %v, error %v", updated, err)
+	}
+	missing := &model.Lab{ID: lab.ID + 1_000_000_000, Title: "missing"}
+	if updated, err := f.repo.UpdateLabFields(ctx, missing, 1, "title"); err != nil || updated {
+		return fmt.Errorf("update fields of missing lab: updated %v, error %v", updated, err)
+	}
+	got, err = f.repo.GetLab(ctx, int(lab.ID))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	if got.Title != "after" || got.Version != 2 {
+		return fmt.Errorf("stale update changed the lab: title %q, version %d", got.Title, got.Version)
+	}
+	return nil
+}
+
+func getLabsByTask(ctx context.Context, f *fixture) error {
+	first, err := f.create(ctx, &model.Lab{})
+	if err != nil {
+		return err
+	}
+	second, err := f.create(ctx, &model.Lab{Status: model.LabStatusTrashed})
+	if err != nil {
+		return err
+	}
+	if _, err := f.create(ctx, &model.Lab{TaskID: f.taskID + 1}); err != nil {
+		return err
+	}
+	labs, err := f.repo.GetLabsByTask(ctx, f.taskID)
+	if err != nil {
+		return fmt.Errorf("get labs by task: %w", err)
+	}
+	got := ids(labs)
+	slices.Sort(got)
+	if !slices.Equal(got, []uint{first.ID, second.ID}) {
+		return fmt.Errorf("get labs by task: got %v, want %v", got, []uint{first.ID, second.ID})
+	}
+	return nil
+}
+
+func listFilters(ctx context.Context, f *fixture) error {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	running, err := f.create(ctx, &model.Lab{CreatedAt: base})
+	if err != nil {
+		return err
+	}
+	stopped, err := f.create(ctx, &model.Lab{CreatedAt: base.Add(time.Minute), Status: model.LabStatusStopped})
+	if err != nil {
+		return err
+	}
+	trashed, err := f.create(ctx, &model.Lab{CreatedAt: base.Add(2 * time.Minute), Status: model.LabStatusTrashed})
+	if err != nil {
+		return err
+	}
+	other, err := f.create(ctx, &model.Lab{CreatedAt: base.Add(3 * time.Minute), OwnerID: f.ownerID + 1})
+	if err != nil {
+		return err
+	}
+	pooled, err := f.create(ctx, &model.Lab{CreatedAt: base.Add(4 * time.Minute), Status: model.LabStatusPooled})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range []struct {
+		name   string
+		filter model.LabFilter
+		want   []uint
+	}{
+		{"task without status hides trash and pool", model.LabFilter{TaskID: f.taskID}, []uint{running.ID, stopped.ID, other.ID}},
+		{"owner", model.LabFilter{TaskID: f.taskID, OwnerID: f.ownerID}, []uint{running.ID, stopped.ID}},
+		{"status", model.LabFilter{TaskID: f.taskID, Status: model.LabStatusStopped}, []uint{stopped.ID}},
+		{"trashed status", model.LabFilter{TaskID: f.taskID, Status: model.LabStatusTrashed}, []uint{trashed.ID}},
+		{"pooled status", model.LabFilter{TaskID: f.taskID, Status: model.LabStatusPooled}, []uint{pooled.ID}},
+		{"created range is half-open", model.LabFilter{TaskID: f.taskID, CreatedFrom: base.Add(time.Minute), CreatedTo: base.Add(3 * time.Minute)}, []uint{stopped.ID}},
+		{"limit", model.LabFilter{TaskID: f.taskID, Limit: 2}, []uint{running.ID, stopped.ID}},
+		{"descending", model.LabFilter{TaskID: f.taskID, Desc: true}, []uint{other.ID, stopped.ID, running.ID}},
+	} {
+		labs, err := f.repo.ListLabs(ctx, c.filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+		if err := expectIDs(c.name, labs, c.want...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listLabels(ctx context.Context, f *fixture) error {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	linux, err := f.create(ctx, &model.Lab{CreatedAt: base, Labels: map[string]string{"course": "linux-101", "team": "blue"}})
+	if err != nil {
+		return err
+	}
+	network, err := f.create(ctx, &model.Lab{CreatedAt: base.Add(time.Minute), Labels: map[string]string{"course": "net-201"}})
+	if err != nil {
+		return err
+	}
+	if _, err := f.create(ctx, &model.Lab{CreatedAt: base.Add(2 * time.Minute)}); err != nil {
+		return err
+	}
+
+	for _, c := range []struct {
+		name   string
+		filter model.LabFilter
+		want   []uint
+	}{
+		{"label value", model.LabFilter{Labels: map[string]string{"course": "linux-101"}}, []uint{linux.ID}},
+		{"all label values", model.LabFilter{Labels: map[string]string{"course": "linux-101", "team": "blue"}}, []uint{linux.ID}},
+		{"label value mismatch", model.LabFilter{Labels: map[string]string{"course": "linux-101", "team": "red"}}, []uint{}},
+		{"label key", model.LabFilter{LabelKeys: []string{"course"}}, []uint{linux.ID, network.ID}},
+		{"label key and value", model.LabFilter{LabelKeys: []string{"team"}, Labels: map[string]string{"course": "linux-101"}}, []uint{linux.ID}},
+		{"missing label key", model.LabFilter{LabelKeys: []string{"missing"}}, []uint{}},
+	} {
+		c.filter.TaskID = f.taskID
+		labs, err := f.repo.ListLabs(ctx, c.filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+		if err := expectIDs(c.name, labs, c.want...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Лаборатории с одинаковым временем упорядочиваются по ID, и курсор не теряет и не повторяет записи
+func listPaging(ctx context.Context, f *fixture) error {
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var all []uint
+	for i := range 5 {
+		// Две пары с одинаковым временем создания
+		lab, err := f.create(ctx, &model.Lab{CreatedAt: created.Add(time.Duration(i/2) * time.Minute)})
+		if err != nil {
+			return err
+		}
+		all = append(all, lab.ID)
+	}
+
+	for _, desc := range []bool{false, true} {
+		want := slices.Clone(all)
+		if desc {
+			slices.Reverse(want)
+		}
+		var got []uint
+		filter := model.LabFilter{TaskID: f.taskID, Desc: desc, Limit: 2}
+		for page := 0; ; page++ {
+			if page > len(all) {
+				return errors.New("paging does not terminate")
+			}
+			labs, err := f.repo.ListLabs(ctx, filter)
+			if err != nil {
+				return fmt.Errorf("list page %d: %w", page, err)
+			}
+			got = append(got, ids(labs)...)
+			if len(labs) < filter.Limit {
+				break
+			}
+			last := labs[len(labs)-1]
+			filter.After = &model.LabCursor{Time: last.CreatedAt, ID: last.ID}
+		}
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("paging desc=%v: got %v, want %v", desc, got, want)
+		}
+	}
+
+	// Сортировка по времени обновления не зависит от времени создания
+	first, err := f.repo.GetLab(ctx, int(all[0]))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	first.Title = "touched"
+	if err := f.repo.UpdateLab(ctx, first); err != nil {
+		return fmt.Errorf("update lab: %w", err)
+	}
+	labs, err := f.repo.ListLabs(ctx, model.LabFilter{TaskID: f.taskID, SortBy: model.LabSortUpdatedAt, Desc: true, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("list by updated_at: %w", err)
+	}
+	return expectIDs("sort by updated_at", labs, first.ID)
+}
+
+// Время в условиях сравнивается как момент, а не как запись в конкретном часовом поясе
+func listTimeZones(ctx context.Context, f *fixture) error {
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	created := time.Now().Add(-time.Hour).Truncate(time.Second).In(zone)
+	lab, err := f.create(ctx, &model.Lab{CreatedAt: created})
+	if err != nil {
+		return err
+	}
+	for _, c := range []struct {
+		name   string
+		filter model.LabFilter
+		want   []uint
+	}{
+		{"from in UTC", model.LabFilter{CreatedFrom: created.UTC()}, []uint{lab.ID}},
+		{"to in UTC", model.LabFilter{CreatedTo: created.UTC()}, []uint{}},
+		{"from in another zone", model.LabFilter{CreatedFrom: created.Add(-time.Second).In(time.FixedZone("UTC-8", -8*60*60))}, []uint{lab.ID}},
+		{"to just after", model.LabFilter{CreatedTo: created.Add(time.Second).UTC()}, []uint{lab.ID}},
+	} {
+		c.filter.TaskID = f.taskID
+		labs, err := f.repo.ListLabs(ctx, c.filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+		if err := expectIDs(c.name, labs, c.want...); err != nil {
+			return err
+		}
+	}
+	got, err := f.repo.GetLab(ctx, int(lab.ID))
+	if err != nil {
+		return fmt.Errorf("get lab: %w", err)
+	}
+	if !sameTime(got.CreatedAt, created) {
+		return fmt.Errorf("created_at = %v, want %v", got.CreatedAt, created)
+	}
+	return nil
+}
+
+func expiredLabs(ctx context.Context, f *fixture) error {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	expired, err := f.create(ctx, &model.Lab{ExpiresAt: &past, Containers: []model.LabContainer{{ServiceName: "web"}}})
+	if err != nil {
+		return err
+	}
+	for _, lab := range []*model.Lab{
+		{ExpiresAt: &future},
+		{},
+		{ExpiresAt: &past, Status: model.LabStatusExpired},
+		{ExpiresAt: &past, Status: model.LabStatusTrashed},
+	} {
+		if _, err := f.create(ctx, lab); err != nil {
+			return err
+		}
+	}
+	labs, err := f.repo.GetExpiredLabs(ctx, now)
+	if err != nil {
+		return fmt.Errorf("get expired labs: %w", err)
+	}
+	labs = ownLabs(labs, f.taskID)
+	if err := expectIDs("expired labs", labs, expired.ID); err != nil {
+		return err
+	}
+	if len(labs[0].Containers) != 1 {
+		return fmt.Errorf("expired labs: containers are not loaded")
+	}
+	return nil
+}
+
+func trashedLabs(ctx context.Context, f *fixture) error {
+	now := time.Now()
+	old, recent := now.Add(-2*time.Hour), now.Add(-time.Minute)
+	purge, err := f.create(ctx, &model.Lab{Status: model.LabStatusTrashed, TrashedAt: &old})
+	if err != nil {
+		return err
+	}
+	if _, err := f.create(ctx, &model.Lab{Status: model.LabStatusTrashed, TrashedAt: &recent}); err != nil {
+		return err
+	}
+	// Восстановленная лаборатория с оставшейся отметкой не удаляется
+	if _, err := f.create(ctx, &model.Lab{Status: model.LabStatusStopped, TrashedAt: &old}); err != nil {
+		return err
+	}
+	labs, err := f.repo.GetTrashedLabs(ctx, now.Add(-time.Hour))
+	if err != nil {
+		return fmt.Errorf("get trashed labs: %w", err)
+	}
+	return expectIDs("trashed labs", ownLabs(labs, f.taskID), purge.ID)
+}
+
+func countLabs(ctx context.Context, f *fixture) error {
+	for _, status := range []string{model.LabStatusRunning, model.LabStatusRunning, model.LabStatusStopped, model.LabStatusExpired, model.LabStatusTrashed} {
+		if _, err := f.create(ctx, &model.Lab{Status: status}); err != nil {
+			return err
+		}
+	}
+	if _, err := f.create(ctx, &model.Lab{OwnerID: f.ownerID + 1}); err != nil {
+		return err
+	}
+
+	owned, err := f.repo.CountOwnerLabs(ctx, f.ownerID)
+	if err != nil {
+		return fmt.Errorf("count owner labs: %w", err)
+	}
+	// Истёкшие и удалённые в корзину лаборатории не занимают квоту
+	if owned != 3 {
+		return fmt.Errorf("count owner labs = %d, want 3", owned)
+	}
+
+	counts, err := f.repo.CountLabs(ctx)
+	if err != nil {
+		return fmt.Errorf("count labs: %w", err)
+	}
+	got := map[string]int64{}
+	for _, count := range counts {
+		if count.TaskID == f.taskID {
+			got[count.Status] = count.Count
+		}
+	}
+	want := map[string]int64{
+		model.LabStatusRunning: 3,
+		model.LabStatusStopped: 1,
+		model.LabStatusExpired: 1,
+		model.LabStatusTrashed: 1,
+	}
+	if len(got) != len(want) {
+		return fmt.Errorf("count labs = %v, want %v", got, want)
+	}
+	for status, count := range want {
+		if got[status] != count {
+			return fmt.Errorf("count labs = %v, want %v", got, want)
+		}
+	}
+	return nil
+}
+
+func deleteLab(ctx context.Context, f *fixture) error {
+	lab, err := f.create(ctx, &model.Lab{Containers: []model.LabContainer{{ServiceName: "web"}, {ServiceName: "db"}}})
+	if err != nil {
+		return err
+	}
+	kept, err := f.create(ctx, &model.Lab{Containers: []model.LabContainer{{ServiceName: "web"}}})
+	if err != nil {
+		return err
+	}
+	if err := f.repo.DeleteLab(ctx, int(lab.ID)); err != nil {
+		return fmt.Errorf("delete lab: %w", err)
+	}
+	if _, err := f.repo.GetLab(ctx, int(lab.ID)); !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("get deleted lab: error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if err := f.repo.DeleteLab(ctx, int(lab.ID)); !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("delete missing lab: error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	got, err := f.repo.GetLab(ctx, int(kept.ID))
+	if err != nil {
+		return fmt.Errorf("get kept lab: %w", err)
+	}
+	if len(got.Containers) != 1 {
+		return fmt.Errorf("containers of another lab were deleted: %+v", got.Containers)
+	}
+	return nil
+}
+
+// Оставляет лаборатории проверки из общих выборок вроде GetExpiredLabs
+func ownLabs(labs []*model.Lab, taskID uint) []*model.Lab {
+	own := []*model.Lab{}
+	for _, lab := range labs {
+		if lab.TaskID == taskID {
+			own = append(own, lab)
+		}
+	}
+	slices.SortFunc(own, func(a, b *model.Lab) int { return int(a.ID) - int(b.ID) })
+	return own
+}
@@ -0,0 +1,69 @@
+package conformance_test
+
+import (
+	"context"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"lab/internal/config"
+	"lab/internal/interfaces"
+	"lab/internal/migrations"
+	"lab/internal/repository"
+	"lab/internal/repository/conformance"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// DSN базы Postgres для проверок; без него Postgres пропускается
+const postgresDSNEnv = "LAB_TEST_POSTGRES_DSN"
+
+func TestSQLite(t *testing.T) {
+	db, err := config.InitDB(config.Config{
+		DBDriver: config.DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "lab.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCases(t, db, repository.NewSQLiteLabRepository(db, slog.Default()))
+}
+
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCases(t, db, repository.NewLabRepository(db, slog.Default()))
+}
+
+// Доводит схему до последней версии и выполняет каждую проверку набора отдельным подтестом
+func runCases(t *testing.T, db *gorm.DB, repo interfaces.LabInterface) {
+	t.Helper()
+	ctx := context.Background()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := migrations.NewMigrator(sqlDB, db.Dialector.Name(), slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range conformance.Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := conformance.RunCase(ctx, repo, c); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"strings"
+)
+
+type ExecAuditRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewExecAuditRepository(db *gorm.DB, logger *slog.Logger) interfaces.ExecAuditInterface {
+	return &ExecAuditRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Экранирует спецсимволы LIKE, чтобы подстрока искалась буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Метод для сохранения записи журнала команд
+func (r *ExecAuditRepository) CreateExecAudit(ctx context.Context, audit *model.ExecAudit) error {
+	if err := r.DB.WithContext(ctx).Create(audit).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while saving exec audit", "error", err, "lab_id", audit.LabID)
+		return err
+	}
+	return nil
+}
+
+// Метод для выборки журнала команд по фильтру, новые первыми
+func (r *ExecAuditRepository) FindExecAudits(ctx context.Context, filter model.ExecAuditFilter) ([]*model.ExecAudit, error) {
+	query := r.DB.WithContext(ctx).Model(&model.ExecAudit{})
+	if filter.LabID != 0 {
+		query = query.Where("lab_id = ?", filter.LabID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.Command != "" {
+		query = query.Where(`LOWER(command) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(filter.Command))+"%")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var audits []*model.ExecAudit
+	if err := query.Order("created_at DESC").Order("id DESC").Find(&audits).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding exec audits", "error", err)
+		return nil, err
+	}
+	return audits, nil
+}
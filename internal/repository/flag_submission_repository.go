@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type FlagSubmissionRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewFlagSubmissionRepository(db *gorm.DB, logger *slog.Logger) interfaces.FlagSubmissionInterface {
+	return &FlagSubmissionRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для сохранения попытки сдачи флага
+func (r *FlagSubmissionRepository) CreateSubmission(ctx context.Context, submission *model.FlagSubmission) error {
+	if err := r.DB.WithContext(ctx).Create(submission).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while saving flag submission", "error", err, "lab_id", submission.LabID)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Flag submission saved", "id", submission.ID, "lab_id", submission.LabID,
+		"correct", submission.Correct, "suspected_sharing", submission.SuspectedSharing)
+	return nil
+}
+
+// Метод для получения попыток сдачи флага в лаборатории, новые первыми
+func (r *FlagSubmissionRepository) GetSubmissions(ctx context.Context, labID uint) ([]*model.FlagSubmission, error) {
+	var submissions []*model.FlagSubmission
+	if err := r.DB.WithContext(ctx).Where("lab_id = ?", labID).Order("created_at DESC").Find(&submissions).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding flag submissions", "error", err, "lab_id", labID)
+		return nil, err
+	}
+	return submissions, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewIdempotencyRepository(db *gorm.DB, logger *slog.Logger) interfaces.IdempotencyInterface {
+	return &IdempotencyRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для сохранения нового ключа идемпотентности. Если у пользователя уже есть такой ключ,
+// запись не создаётся и возвращается false: так два одновременных запроса не выполнятся оба.
+func (r *IdempotencyRepository) CreateRecord(ctx context.Context, record *model.IdempotencyRecord) (bool, error) {
+	result := r.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(record)
+	if result.Error != nil {
+		r.Logger.ErrorContext(ctx, "Error while creating idempotency record", "error", result.Error, "user_id", record.UserID)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Метод для получения записи по ключу пользователя
+func (r *IdempotencyRepository) GetRecord(ctx context.Context, userID uint, key string) (*model.IdempotencyRecord, error) {
+	var record model.IdempotencyRecord
+	if err := r.DB.WithContext(ctx).Where("user_id = ? AND idempotency_key = ?", userID, key).First(&record).Error; err != nil {
+		r.Logger.WarnContext(ctx, "Can not find idempotency record", "user_id", userID, "error", err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Метод для сохранения ответа на запрос
+func (r *IdempotencyRepository) UpdateRecord(ctx context.Context, record *model.IdempotencyRecord) error {
+	if err := r.DB.WithContext(ctx).Save(record).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating idempotency record", "error", err, "id", record.ID)
+		return err
+	}
+	return nil
+}
+
+// Метод для удаления записи, например если запрос завершился ошибкой сервера
+func (r *IdempotencyRepository) DeleteRecord(ctx context.Context, id uint) error {
+	if err := r.DB.WithContext(ctx).Delete(&model.IdempotencyRecord{}, id).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error deleting idempotency record", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// Метод для удаления записей с истёкшим сроком хранения
+func (r *IdempotencyRepository) DeleteExpiredRecords(ctx context.Context, before time.Time) (int64, error) {
+	result := r.DB.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.IdempotencyRecord{})
+	if result.Error != nil {
+		r.Logger.ErrorContext(ctx, "Error deleting expired idempotency records", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
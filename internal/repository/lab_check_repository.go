@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type LabCheckRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewLabCheckRepository(db *gorm.DB, logger *slog.Logger) interfaces.LabCheckInterface {
+	return &LabCheckRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для сохранения результата проверки
+func (r *LabCheckRepository) CreateCheckResult(ctx context.Context, result *model.LabCheckResult) error {
+	if err := r.DB.WithContext(ctx).Create(result).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while saving check result", "error", err, "lab_id", result.LabID)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Check result saved", "id", result.ID, "lab_id", result.LabID)
+	return nil
+}
+
+// Метод для получения истории проверок лаборатории, новые первыми
+func (r *LabCheckRepository) GetCheckResults(ctx context.Context, labID uint) ([]*model.LabCheckResult, error) {
+	var results []*model.LabCheckResult
+	if err := r.DB.WithContext(ctx).Where("lab_id = ?", labID).Order("created_at DESC").Find(&results).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding check results", "error", err, "lab_id", labID)
+		return nil, err
+	}
+	r.Logger.InfoContext(ctx, "Check results found", "lab_id", labID, "count", len(results))
+	return results, nil
+}
@@ -2,27 +2,36 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"gorm.io/gorm"
 	"lab/internal/interfaces"
 	"lab/internal/model"
 	"log/slog"
+	"time"
 )
 
 type LabRepository struct {
 	DB     *gorm.DB
 	Logger *slog.Logger
+	// Условия фильтра по меткам — единственный запрос, который различается между СУБД
+	filterLabels labelFilter
 }
 
+// labelFilter добавляет к запросу условия: метки содержат все пары labels и все ключи keys
+type labelFilter func(query *gorm.DB, labels map[string]string, keys []string) (*gorm.DB, error)
+
 func NewLabRepository(db *gorm.DB, logger *slog.Logger) interfaces.LabInterface {
 	return &LabRepository{
-		DB:     db,
-		Logger: logger,
+		DB:           db,
+		Logger:       logger,
+		filterLabels: postgresLabelFilter,
 	}
 }
 
 // Метод для создания лаборатории (запуска контейнера)
 func (r *LabRepository) CreateLab(ctx context.Context, lab *model.Lab) error {
-	if err := r.DB.Create(lab).Error; err != nil {
+	lab.Version = 1
+	if err := r.DB.WithContext(ctx).Create(lab).Error; err != nil {
 		r.Logger.ErrorContext(ctx, "Error while creating lab", "error", err)
 		return err
 	}
@@ -32,7 +41,8 @@ func (r *LabRepository) CreateLab(ctx context.Context, lab *model.Lab) error {
 
 // Метод для обновления лаборатории
 func (r *LabRepository) UpdateLab(ctx context.Context, lab *model.Lab) error {
-	if err := r.DB.Save(lab).Error; err != nil {
+	lab.Version++
+	if err := r.DB.WithContext(ctx).Save(lab).Error; err != nil {
 		r.Logger.ErrorContext(ctx, "Error while updating lab", "error", err, "lab_id", lab.ID)
 		return err
 	}
@@ -40,14 +50,45 @@ func (r *LabRepository) UpdateLab(ctx context.Context, lab *model.Lab) error {
 	return nil
 }
 
+// Метод для обновления отдельных полей лаборатории при совпадении версии. Значения берутся из lab,
+// columns — имена колонок. Возвращает false, если лаборатории с такой версией нет.
+func (r *LabRepository) UpdateLabFields(ctx context.Context, lab *model.Lab, version uint, columns ...string) (bool, error) {
+	// Без ID условие по версии затронуло бы все лаборатории с этой версией
+	if lab.ID == 0 {
+		return false, gorm.ErrPrimaryKeyRequired
+	}
+	lab.Version = version + 1
+	selected := append([]string{"version", "updated_at"}, columns...)
+	result := r.DB.WithContext(ctx).Model(lab).
+		Where("version = ?", version).
+		Select(selected).
+		Updates(lab)
+	if result.Error != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating lab fields", "error", result.Error, "lab_id", lab.ID)
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		r.Logger.WarnContext(ctx, "Lab version mismatch", "lab_id", lab.ID, "version", version)
+		return false, nil
+	}
+	r.Logger.InfoContext(ctx, "Lab fields updated successfully", "lab_id", lab.ID, "columns", columns, "version", lab.Version)
+	return true, nil
+}
+
 // Метод для удаления лаборатории
 func (r *LabRepository) DeleteLab(ctx context.Context, id int) error {
 	var lab model.Lab
-	if err := r.DB.Where("id = ?", id).First(&lab).Error; err != nil {
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&lab).Error; err != nil {
 		r.Logger.ErrorContext(ctx, "Error finding lab to delete", "error", err, "lab_id", id)
 		return err
 	}
-	if err := r.DB.Delete(&lab).Error; err != nil {
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("lab_id = ?", lab.ID).Delete(&model.LabContainer{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&lab).Error
+	})
+	if err != nil {
 		r.Logger.ErrorContext(ctx, "Error deleting lab", "error", err, "lab_id", id)
 		return err
 	}
@@ -59,7 +100,7 @@ func (r *LabRepository) DeleteLab(ctx context.Context, id int) error {
 func (r *LabRepository) GetLab(ctx context.Context, id int) (*model.Lab, error) {
 	var lab model.Lab
 	// Получаем лабораторию по ID
-	if err := r.DB.Where("id = ?", id).First(&lab).Error; err != nil {
+	if err := r.DB.WithContext(ctx).Preload("Containers", orderContainers).Where("id = ?", id).First(&lab).Error; err != nil {
 		r.Logger.WarnContext(ctx, "Can not find lab by id", "lab_id", id, "error", err)
 		return nil, err
 	}
@@ -71,10 +112,151 @@ func (r *LabRepository) GetLab(ctx context.Context, id int) (*model.Lab, error)
 func (r *LabRepository) GetAllLabs(ctx context.Context) ([]*model.Lab, error) {
 	var labs []*model.Lab
 	// Получаем все лаборатории
-	if err := r.DB.Find(&labs).Error; err != nil {
+	if err := r.DB.WithContext(ctx).Preload("Containers", orderContainers).Find(&labs).Error; err != nil {
 		r.Logger.ErrorContext(ctx, "Error finding labs", "error", err)
 		return nil, err
 	}
 	r.Logger.InfoContext(ctx, "Labs found", "labs_count", len(labs))
 	return labs, nil
 }
+
+// Метод для выборки страницы лабораторий по фильтру с сортировкой по времени и ID
+func (r *LabRepository) ListLabs(ctx context.Context, filter model.LabFilter) ([]*model.Lab, error) {
+	query := r.DB.WithContext(ctx).Preload("Containers", orderContainers)
+	if filter.TaskID != 0 {
+		query = query.Where("task_id = ?", filter.TaskID)
+	}
+	if filter.OwnerID != 0 {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		// Корзина и ещё не выданные лаборатории тёплого пула показываются только по явному статусу
+		query = query.Where("status NOT IN ?", []string{model.LabStatusTrashed, model.LabStatusPooled})
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	query, err := r.filterLabels(query, filter.Labels, filter.LabelKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	sortBy := model.LabSortCreatedAt
+	if filter.SortBy == model.LabSortUpdatedAt {
+		sortBy = model.LabSortUpdatedAt
+	}
+	direction, compare := "ASC", ">"
+	if filter.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if filter.After != nil {
+		query = query.Where("("+sortBy+", id) "+compare+" (?, ?)", filter.After.Time, filter.After.ID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var labs []*model.Lab
+	if err := query.Order(sortBy + " " + direction).Order("id " + direction).Find(&labs).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error listing labs", "error", err)
+		return nil, err
+	}
+	return labs, nil
+}
+
+// Метки хранятся в jsonb: пары проверяются оператором @>, наличие ключа — через ->>
+func postgresLabelFilter(query *gorm.DB, labels map[string]string, keys []string) (*gorm.DB, error) {
+	if len(labels) > 0 {
+		encoded, err := json.Marshal(labels)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("labels @> CAST(? AS jsonb)", string(encoded))
+	}
+	for _, key := range keys {
+		query = query.Where("labels ->> CAST(? AS text) IS NOT NULL", key)
+	}
+	return query, nil
+}
+
+// Метод для обновления контейнера сервиса лаборатории
+func (r *LabRepository) UpdateLabContainer(ctx context.Context, container *model.LabContainer) error {
+	if err := r.DB.WithContext(ctx).Save(container).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating lab container", "error", err, "container_id", container.ID)
+		return err
+	}
+	return nil
+}
+
+// Контейнеры сервисов хранятся в порядке запуска, поэтому загружаем их по возрастанию ID
+func orderContainers(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// Метод для получения всех лабораторий задания
+func (r *LabRepository) GetLabsByTask(ctx context.Context, taskID uint) ([]*model.Lab, error) {
+	var labs []*model.Lab
+	if err := r.DB.WithContext(ctx).Where("task_id = ?", taskID).Find(&labs).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding labs by task", "error", err, "task_id", taskID)
+		return nil, err
+	}
+	return labs, nil
+}
+
+// Метод для получения лабораторий с истёкшим сроком жизни, которые ещё не остановлены по сроку
+func (r *LabRepository) GetExpiredLabs(ctx context.Context, now time.Time) ([]*model.Lab, error) {
+	var labs []*model.Lab
+	err := r.DB.WithContext(ctx).Preload("Containers", orderContainers).
+		Where("expires_at <= ? AND status NOT IN ?", now, []string{model.LabStatusExpired, model.LabStatusTrashed}).
+		Find(&labs).Error
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding expired labs", "error", err)
+		return nil, err
+	}
+	return labs, nil
+}
+
+// Метод для получения лабораторий, удалённых в корзину не позже before
+func (r *LabRepository) GetTrashedLabs(ctx context.Context, before time.Time) ([]*model.Lab, error) {
+	var labs []*model.Lab
+	err := r.DB.WithContext(ctx).Preload("Containers", orderContainers).
+		Where("status = ? AND trashed_at <= ?", model.LabStatusTrashed, before).
+		Find(&labs).Error
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding trashed labs", "error", err)
+		return nil, err
+	}
+	return labs, nil
+}
+
+// Метод для подсчёта лабораторий по заданиям и состояниям
+func (r *LabRepository) CountLabs(ctx context.Context) ([]model.LabCount, error) {
+	var counts []model.LabCount
+	err := r.DB.WithContext(ctx).Model(&model.Lab{}).
+		Select("task_id, status, COUNT(*) AS count").
+		Group("task_id, status").
+		Scan(&counts).Error
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Error counting labs", "error", err)
+		return nil, err
+	}
+	return counts, nil
+}
+
+// Метод для подсчёта неистёкших лабораторий пользователя, не считая корзины
+func (r *LabRepository) CountOwnerLabs(ctx context.Context, ownerID uint) (int64, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&model.Lab{}).
+		Where("owner_id = ? AND status NOT IN ?", ownerID, []string{model.LabStatusExpired, model.LabStatusTrashed}).
+		Count(&count).Error
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Error counting owner labs", "error", err, "owner_id", ownerID)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"log/slog"
+	"sort"
+)
+
+// NewSQLiteLabRepository возвращает хранилище лабораторий поверх файла SQLite для локальной разработки.
+// Все запросы, кроме фильтра по меткам, совпадают с Postgres.
+func NewSQLiteLabRepository(db *gorm.DB, logger *slog.Logger) interfaces.LabInterface {
+	return &LabRepository{
+		DB:           db,
+		Logger:       logger,
+		filterLabels: sqliteLabelFilter,
+	}
+}
+
+// Метки хранятся JSON-строкой и разбираются функциями json1. Ключи меток проверяются
+// в сервисе и не содержат кавычек, поэтому их можно подставить в путь JSON как есть.
+func sqliteLabelFilter(query *gorm.DB, labels map[string]string, keys []string) (*gorm.DB, error) {
+	names := make([]string, 0, len(labels))
+	for key := range labels {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		query = query.Where("json_extract(labels, ?) = ?", labelPath(key), labels[key])
+	}
+	for _, key := range keys {
+		query = query.Where("json_type(labels, ?) IS NOT NULL", labelPath(key))
+	}
+	return query, nil
+}
+
+func labelPath(key string) string {
+	return `$."` + key + `"`
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type LabSecretRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewLabSecretRepository(db *gorm.DB, logger *slog.Logger) interfaces.LabSecretInterface {
+	return &LabSecretRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для сохранения секретов лаборатории
+func (r *LabSecretRepository) CreateSecrets(ctx context.Context, secrets []*model.LabSecret) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	if err := r.DB.WithContext(ctx).Create(secrets).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while saving lab secrets", "error", err)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Lab secrets saved", "lab_id", secrets[0].LabID, "count", len(secrets))
+	return nil
+}
+
+// Метод для получения секретов лаборатории
+func (r *LabSecretRepository) GetSecrets(ctx context.Context, labID uint) ([]*model.LabSecret, error) {
+	var secrets []*model.LabSecret
+	if err := r.DB.WithContext(ctx).Where("lab_id = ?", labID).Order("id").Find(&secrets).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding lab secrets", "error", err, "lab_id", labID)
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// Метод для удаления секретов лаборатории
+func (r *LabSecretRepository) DeleteSecrets(ctx context.Context, labID uint) error {
+	if err := r.DB.WithContext(ctx).Where("lab_id = ?", labID).Delete(&model.LabSecret{}).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error deleting lab secrets", "error", err, "lab_id", labID)
+		return err
+	}
+	return nil
+}
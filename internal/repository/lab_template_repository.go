@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type LabTemplateRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewLabTemplateRepository(db *gorm.DB, logger *slog.Logger) interfaces.LabTemplateInterface {
+	return &LabTemplateRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для создания шаблона
+func (r *LabTemplateRepository) CreateTemplate(ctx context.Context, template *model.LabTemplate) error {
+	if err := r.DB.WithContext(ctx).Create(template).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while creating template", "error", err)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Template created successfully", "template_id", template.ID)
+	return nil
+}
+
+// Метод для обновления шаблона
+func (r *LabTemplateRepository) UpdateTemplate(ctx context.Context, template *model.LabTemplate) error {
+	if err := r.DB.WithContext(ctx).Save(template).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating template", "error", err, "template_id", template.ID)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Template updated successfully", "template_id", template.ID)
+	return nil
+}
+
+// Метод для удаления шаблона
+func (r *LabTemplateRepository) DeleteTemplate(ctx context.Context, id uint) error {
+	result := r.DB.WithContext(ctx).Delete(&model.LabTemplate{}, id)
+	if result.Error != nil {
+		r.Logger.ErrorContext(ctx, "Error deleting template", "error", result.Error, "template_id", id)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	r.Logger.InfoContext(ctx, "Template deleted successfully", "template_id", id)
+	return nil
+}
+
+// Метод для получения шаблона по ID
+func (r *LabTemplateRepository) GetTemplate(ctx context.Context, id uint) (*model.LabTemplate, error) {
+	var template model.LabTemplate
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&template).Error; err != nil {
+		r.Logger.WarnContext(ctx, "Can not find template by id", "template_id", id, "error", err)
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Метод для получения всех шаблонов
+func (r *LabTemplateRepository) GetAllTemplates(ctx context.Context) ([]*model.LabTemplate, error) {
+	var templates []*model.LabTemplate
+	if err := r.DB.WithContext(ctx).Order("id").Find(&templates).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding templates", "error", err)
+		return nil, err
+	}
+	return templates, nil
+}
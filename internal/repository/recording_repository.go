@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type RecordingRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewRecordingRepository(db *gorm.DB, logger *slog.Logger) interfaces.RecordingInterface {
+	return &RecordingRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для сохранения записи терминальной сессии
+func (r *RecordingRepository) CreateRecording(ctx context.Context, recording *model.TerminalRecording) error {
+	if err := r.DB.WithContext(ctx).Create(recording).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while creating recording", "error", err, "lab_id", recording.LabID)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Recording created", "id", recording.ID, "lab_id", recording.LabID)
+	return nil
+}
+
+// Метод для обновления записи терминальной сессии
+func (r *RecordingRepository) UpdateRecording(ctx context.Context, recording *model.TerminalRecording) error {
+	if err := r.DB.WithContext(ctx).Save(recording).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating recording", "error", err, "id", recording.ID)
+		return err
+	}
+	return nil
+}
+
+// Метод для получения записи по ID
+func (r *RecordingRepository) GetRecording(ctx context.Context, id uint) (*model.TerminalRecording, error) {
+	var recording model.TerminalRecording
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&recording).Error; err != nil {
+		r.Logger.WarnContext(ctx, "Can not find recording by id", "id", id, "error", err)
+		return nil, err
+	}
+	return &recording, nil
+}
+
+// Метод для получения записей лаборатории, новые первыми
+func (r *RecordingRepository) GetRecordingsByLab(ctx context.Context, labID uint) ([]*model.TerminalRecording, error) {
+	var recordings []*model.TerminalRecording
+	if err := r.DB.WithContext(ctx).Where("lab_id = ?", labID).Order("started_at DESC").Find(&recordings).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding recordings", "error", err, "lab_id", labID)
+		return nil, err
+	}
+	return recordings, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type WarmPoolRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewWarmPoolRepository(db *gorm.DB, logger *slog.Logger) interfaces.WarmPoolInterface {
+	return &WarmPoolRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для создания или замены настроек пула задания; время создания существующего пула сохраняется
+func (r *WarmPoolRepository) SavePool(ctx context.Context, pool *model.WarmPool) error {
+	err := r.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "schedule", "time_zone", "updated_at"}),
+	}).Create(pool).Error
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Error while saving warm pool", "error", err, "task_id", pool.TaskID)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Warm pool saved", "task_id", pool.TaskID, "size", pool.Size)
+	return nil
+}
+
+// Метод для получения настроек пула задания
+func (r *WarmPoolRepository) GetPool(ctx context.Context, taskID uint) (*model.WarmPool, error) {
+	var pool model.WarmPool
+	if err := r.DB.WithContext(ctx).Where("task_id = ?", taskID).First(&pool).Error; err != nil {
+		r.Logger.WarnContext(ctx, "Can not find warm pool", "task_id", taskID, "error", err)
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// Метод для получения настроек всех пулов
+func (r *WarmPoolRepository) GetAllPools(ctx context.Context) ([]*model.WarmPool, error) {
+	var pools []*model.WarmPool
+	if err := r.DB.WithContext(ctx).Order("task_id").Find(&pools).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding warm pools", "error", err)
+		return nil, err
+	}
+	return pools, nil
+}
+
+// Метод для удаления настроек пула; лаборатории пула удаляет сервис
+func (r *WarmPoolRepository) DeletePool(ctx context.Context, taskID uint) error {
+	result := r.DB.WithContext(ctx).Where("task_id = ?", taskID).Delete(&model.WarmPool{})
+	if result.Error != nil {
+		r.Logger.ErrorContext(ctx, "Error deleting warm pool", "error", result.Error, "task_id", taskID)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	r.Logger.InfoContext(ctx, "Warm pool deleted", "task_id", taskID)
+	return nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+)
+
+type WebhookRepository struct {
+	DB     *gorm.DB
+	Logger *slog.Logger
+}
+
+func NewWebhookRepository(db *gorm.DB, logger *slog.Logger) interfaces.WebhookInterface {
+	return &WebhookRepository{
+		DB:     db,
+		Logger: logger,
+	}
+}
+
+// Метод для создания подписки на вебхук
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	if err := r.DB.WithContext(ctx).Create(subscription).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while creating webhook subscription", "error", err)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Webhook subscription created", "id", subscription.ID, "url", subscription.URL)
+	return nil
+}
+
+// Метод для обновления подписки на вебхук
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	if err := r.DB.WithContext(ctx).Save(subscription).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating webhook subscription", "error", err, "id", subscription.ID)
+		return err
+	}
+	return nil
+}
+
+// Метод для удаления подписки вместе с историей доставок
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.WebhookSubscription{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		deliveries := tx.Model(&model.WebhookDelivery{}).Select("id").Where("subscription_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&model.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("subscription_id = ?", id).Delete(&model.WebhookDelivery{}).Error
+	})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Error deleting webhook subscription", "error", err, "id", id)
+		return err
+	}
+	r.Logger.InfoContext(ctx, "Webhook subscription deleted", "id", id)
+	return nil
+}
+
+// Метод для получения подписки по ID
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uint) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&subscription).Error; err != nil {
+		r.Logger.WarnContext(ctx, "Can not find webhook subscription by id", "id", id, "error", err)
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Метод для получения всех подписок
+func (r *WebhookRepository) GetAllSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	if err := r.DB.WithContext(ctx).Order("id").Find(&subscriptions).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding webhook subscriptions", "error", err)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Метод для получения включённых подписок
+func (r *WebhookRepository) GetActiveSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	var subscriptions []*model.WebhookSubscription
+	if err := r.DB.WithContext(ctx).Where("active = ?", true).Order("id").Find(&subscriptions).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding active webhook subscriptions", "error", err)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Метод для создания доставки
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := r.DB.WithContext(ctx).Omit("AttemptLog").Create(delivery).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while creating webhook delivery", "error", err, "subscription_id", delivery.SubscriptionID)
+		return err
+	}
+	return nil
+}
+
+// Метод для обновления состояния доставки
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := r.DB.WithContext(ctx).Omit("AttemptLog").Save(delivery).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while updating webhook delivery", "error", err, "id", delivery.ID)
+		return err
+	}
+	return nil
+}
+
+// Метод для получения доставки вместе с попытками
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.DB.WithContext(ctx).Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("attempt") }).
+		Where("id = ?", id).First(&delivery).Error
+	if err != nil {
+		r.Logger.WarnContext(ctx, "Can not find webhook delivery by id", "id", id, "error", err)
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Метод для получения доставок подписки, новые первыми
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID uint) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	if err := r.DB.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("id DESC").Find(&deliveries).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding webhook deliveries", "error", err, "subscription_id", subscriptionID)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Метод для получения незавершённых доставок, например после перезапуска сервиса
+func (r *WebhookRepository) GetPendingDeliveries(ctx context.Context) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	if err := r.DB.WithContext(ctx).Where("status = ?", model.DeliveryPending).Order("id").Find(&deliveries).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error finding pending webhook deliveries", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Метод для сохранения попытки доставки
+func (r *WebhookRepository) CreateAttempt(ctx context.Context, attempt *model.WebhookAttempt) error {
+	if err := r.DB.WithContext(ctx).Create(attempt).Error; err != nil {
+		r.Logger.ErrorContext(ctx, "Error while saving webhook attempt", "error", err, "delivery_id", attempt.DeliveryID)
+		return err
+	}
+	return nil
+}
@@ -3,34 +3,111 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"lab/internal/handlers"
+	"lab/internal/metrics"
+	"lab/internal/middleware"
 )
 
-func SetupRoutes(router *gin.Engine, labHandler *handlers.LabHandler) {
+func SetupRoutes(router *gin.Engine, labHandler *handlers.LabHandler, checkHandler *handlers.CheckHandler, templateHandler *handlers.TemplateHandler, flagHandler *handlers.FlagHandler, fileHandler *handlers.FileHandler, terminalHandler *handlers.TerminalHandler, auditHandler *handlers.AuditHandler, eventHandler *handlers.EventHandler, webhookHandler *handlers.WebhookHandler, healthHandler *handlers.HealthHandler, warmPoolHandler *handlers.WarmPoolHandler, idempotency gin.HandlerFunc) {
 	// Группа маршрутов для лаборатории
 	labGroup := router.Group("/labs")
 	{
-		// Создание лаборатории
-		labGroup.POST("", labHandler.CreateLabHandler)
+		// Создание лаборатории (только для авторизованных) и постраничный список лабораторий.
+		// Создание и операции жизненного цикла принимают заголовок Idempotency-Key
+		labGroup.POST("", middleware.RequireAuth(), idempotency, labHandler.CreateLabHandler)
+		labGroup.GET("", labHandler.ListLabsHandler)
 
-		// Обновление лаборатории
-		labGroup.PUT("/:id", labHandler.UpdateLabHandler)
+		// Частичное обновление лаборатории с проверкой версии через If-Match
+		labGroup.PATCH("/:id", labHandler.UpdateLabHandler)
 
-		// Удаление лаборатории
-		labGroup.POST("/:id/delete", labHandler.DeleteLabHandler)
+		// Удаление лаборатории в корзину и восстановление из неё
+		labGroup.POST("/:id/delete", idempotency, labHandler.DeleteLabHandler)
+		labGroup.POST("/:id/restore", labHandler.RestoreLabHandler)
 
 		// Запуск лаборатории
-		labGroup.POST("/:id/start", labHandler.StartLabHandler)
+		labGroup.POST("/:id/start", idempotency, labHandler.StartLabHandler)
 
 		// Остановка лаборатории
-		labGroup.POST("/:id/stop", labHandler.StopLabHandler)
+		labGroup.POST("/:id/stop", idempotency, labHandler.StopLabHandler)
 
 		// Выполнение команды в лаборатории
 		labGroup.POST("/:id/execute-command", labHandler.ExecuteCommandHandler)
 
 		labGroup.GET("/:id", labHandler.GetLabHandler)
 
-		labGroup.POST("/:id/commit", labHandler.CommitLabHandler)
+		labGroup.POST("/:id/commit", idempotency, labHandler.CommitLabHandler)
 
 		labGroup.POST("/:id/deleteCommits", labHandler.DeleteCommitLabHandler)
+
+		// Автоматическая проверка лаборатории и история проверок
+		labGroup.POST("/:id/check", checkHandler.CheckLabHandler)
+		labGroup.GET("/:id/checks", checkHandler.GetCheckHistoryHandler)
+
+		// Сдача флага CTF и история попыток
+		labGroup.POST("/:id/flag", flagHandler.SubmitFlagHandler)
+		labGroup.GET("/:id/flag/submissions", middleware.RequireAdmin(), flagHandler.GetSubmissionsHandler)
+
+		// Загрузка и скачивание файлов контейнера
+		labGroup.PUT("/:id/files", fileHandler.UploadFileHandler)
+		labGroup.GET("/:id/files", fileHandler.DownloadFileHandler)
+
+		// Просмотр файловой системы контейнера
+		labGroup.GET("/:id/fs", fileHandler.ListDirHandler)
+		labGroup.GET("/:id/fs/content", fileHandler.PreviewFileHandler)
+		labGroup.PUT("/:id/fs/content", fileHandler.SaveFileHandler)
+
+		// Терминал через websocket, список записей терминальных сессий, скачивание и воспроизведение записи.
+		// Записи доступны владельцу лаборатории и администратору
+		labGroup.GET("/:id/terminal", terminalHandler.TerminalHandler)
+		labGroup.GET("/:id/recordings", terminalHandler.GetRecordingsHandler)
+		labGroup.GET("/:id/recordings/:rid", terminalHandler.DownloadRecordingHandler)
+		labGroup.GET("/:id/recordings/:rid/stream", terminalHandler.StreamRecordingHandler)
+
+		// Журнал команд, выполненных в лаборатории
+		labGroup.GET("/:id/audit", auditHandler.GetLabAuditHandler)
+	}
+
+	// Проверки живости и готовности для оркестратора
+	router.GET("/healthz", healthHandler.HealthzHandler)
+	router.GET("/readyz", healthHandler.ReadyzHandler)
+
+	// Метрики Prometheus
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Поток событий жизненного цикла лабораторий (SSE или WebSocket), только для авторизованных
+	router.GET("/events", middleware.RequireAuth(), eventHandler.EventsHandler)
+
+	// Поиск по журналу команд всех лабораторий
+	router.GET("/audit", middleware.RequireAdmin(), auditHandler.SearchAuditHandler)
+
+	// Шаблоны лабораторий из нескольких контейнеров, управляются администратором
+	templateGroup := router.Group("/templates", middleware.RequireAdmin())
+	{
+		templateGroup.POST("", templateHandler.CreateTemplateHandler)
+		templateGroup.GET("", templateHandler.GetAllTemplatesHandler)
+		templateGroup.GET("/:id", templateHandler.GetTemplateHandler)
+		templateGroup.PUT("/:id", templateHandler.UpdateTemplateHandler)
+		templateGroup.DELETE("/:id", templateHandler.DeleteTemplateHandler)
+	}
+
+	// Тёплые пулы заранее созданных лабораторий по заданиям, управляются администратором
+	poolGroup := router.Group("/pools", middleware.RequireAdmin())
+	{
+		poolGroup.GET("", warmPoolHandler.GetAllPoolsHandler)
+		poolGroup.GET("/:task_id", warmPoolHandler.GetPoolHandler)
+		poolGroup.PUT("/:task_id", warmPoolHandler.SavePoolHandler)
+		poolGroup.DELETE("/:task_id", warmPoolHandler.DeletePoolHandler)
+	}
+
+	// Подписки на вебхуки и история доставок, управляются администратором
+	webhookGroup := router.Group("/webhooks", middleware.RequireAdmin())
+	{
+		webhookGroup.POST("", webhookHandler.CreateWebhookHandler)
+		webhookGroup.GET("", webhookHandler.GetAllWebhooksHandler)
+		webhookGroup.GET("/:id", webhookHandler.GetWebhookHandler)
+		webhookGroup.PUT("/:id", webhookHandler.UpdateWebhookHandler)
+		webhookGroup.DELETE("/:id", webhookHandler.DeleteWebhookHandler)
+		webhookGroup.GET("/:id/deliveries", webhookHandler.GetDeliveriesHandler)
+		webhookGroup.GET("/deliveries/:id", webhookHandler.GetDeliveryHandler)
+		webhookGroup.POST("/deliveries/:id/redeliver", webhookHandler.RedeliverHandler)
 	}
 }
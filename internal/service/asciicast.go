@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Типы событий asciicast v2
+const (
+	castOutput = "o"
+	castInput  = "i"
+	castResize = "r"
+)
+
+// castHeader — первая строка файла asciicast v2
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// castWriter пишет терминальную сессию в файл asciicast v2: заголовок и по одному событию
+// [время, тип, данные] на строку. Незавершённые UTF-8 последовательности переносятся
+// в следующее событие того же типа, чтобы строки событий оставались валидным JSON.
+type castWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	buf     *bufio.Writer
+	start   time.Time
+	pending map[string][]byte
+	size    int64
+	closed  bool
+}
+
+func newCastWriter(filePath string, header castHeader) (*castWriter, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+	w := &castWriter{
+		file:    file,
+		buf:     bufio.NewWriter(file),
+		start:   time.Now(),
+		pending: make(map[string][]byte),
+	}
+	header.Version = 2
+	header.Timestamp = w.start.Unix()
+	if err := w.writeLine(header); err != nil {
+		file.Close()
+		os.Remove(filePath)
+		return nil, err
+	}
+	return w, nil
+}
+
+// Event записывает событие с временем от начала сессии
+func (w *castWriter) Event(eventType string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	data = append(w.pending[eventType], data...)
+	cut := len(data)
+	// Отрезаем незавершённую последовательность в конце, она не длиннее utf8.UTFMax-1 байт
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	w.pending[eventType] = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return nil
+	}
+	elapsed := time.Since(w.start).Seconds()
+	return w.writeLine([]any{elapsed, eventType, string(data[:cut])})
+}
+
+// Resize записывает изменение размера терминала
+func (w *castWriter) Resize(cols, rows int) error {
+	return w.Event(castResize, []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// Close дописывает буфер на диск и возвращает длительность и размер записи
+func (w *castWriter) Close() (time.Duration, int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, w.size, nil
+	}
+	w.closed = true
+	duration := time.Since(w.start)
+	flushErr := w.buf.Flush()
+	closeErr := w.file.Close()
+	if flushErr != nil {
+		return duration, w.size, fmt.Errorf("failed to flush recording: %w", flushErr)
+	}
+	if closeErr != nil {
+		return duration, w.size, fmt.Errorf("failed to close recording: %w", closeErr)
+	}
+	return duration, w.size, nil
+}
+
+func (w *castWriter) writeLine(value any) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode recording event: %w", err)
+	}
+	line = append(line, '\n')
+	n, err := w.buf.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
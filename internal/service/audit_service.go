@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"lab/internal/interfaces"
+	"lab/internal/logging"
+	"lab/internal/model"
+	"log/slog"
+	"time"
+)
+
+// Максимальная длина вывода команды, сохраняемого в журнале
+const maxAuditOutput = 4096
+
+// Размер страницы журнала по умолчанию и максимальный
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditService ведёт журнал команд, выполненных пользователями в контейнерах лабораторий
+type AuditService struct {
+	AuditRepository interfaces.ExecAuditInterface
+	Redactor        *logging.Redactor // Вырезает секреты лабораторий из команды и вывода
+	Logger          *slog.Logger
+}
+
+func NewAuditService(auditRepository interfaces.ExecAuditInterface, redactor *logging.Redactor, logger *slog.Logger) *AuditService {
+	return &AuditService{
+		AuditRepository: auditRepository,
+		Redactor:        redactor,
+		Logger:          logger,
+	}
+}
+
+// RecordExec сохраняет выполненную команду. Секреты вырезаются до обрезки вывода, чтобы не сохранить
+// их начало. Ошибка записи только логируется, чтобы сбой журнала не ломал саму команду.
+func (s *AuditService) RecordExec(ctx context.Context, labID, userID uint, containerID, command string, exitCode int, duration time.Duration, output string) {
+	audit := &model.ExecAudit{
+		LabID:       labID,
+		UserID:      userID,
+		ContainerID: containerID,
+		Command:     sanitizeText(s.Redactor.Redact(command)),
+		ExitCode:    exitCode,
+		DurationMs:  duration.Milliseconds(),
+		Output:      truncate(s.Redactor.Redact(output), maxAuditOutput),
+	}
+	if err := s.AuditRepository.CreateExecAudit(ctx, audit); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to write exec audit", "error", err, "lab_id", labID, "user_id", userID)
+	}
+}
+
+// FindExecs возвращает записи журнала по фильтру, новые первыми
+func (s *AuditService) FindExecs(ctx context.Context, filter model.ExecAuditFilter) ([]*model.ExecAudit, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	audits, err := s.AuditRepository.FindExecAudits(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exec audit: %w", err)
+	}
+	return audits, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"lab/internal/interfaces"
+	"lab/internal/logging"
+	"lab/internal/model"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Хранилище журнала, запоминающее последнюю запись
+type recordingAuditRepository struct {
+	interfaces.ExecAuditInterface
+	audit *model.ExecAudit
+}
+
+func (r *recordingAuditRepository) CreateExecAudit(ctx context.Context, audit *model.ExecAudit) error {
+	r.audit = audit
+	return nil
+}
+
+func TestRecordExecSanitizesOutput(t *testing.T) {
+	redactor := logging.NewRedactor(slog.Default().Handler())
+	redactor.Add("s3cr3t")
+	for _, tc := range []struct {
+		name   string
+		output string
+	}{
+		{name: "invalid utf-8", output: "bin\xff\xfeary s3cr3t"},
+		{name: "nul", output: "a\x00b\x00s3cr3t"},
+		{name: "cut inside rune", output: strings.Repeat("a", maxAuditOutput-1) + "ж"},
+		{name: "long", output: strings.Repeat("\x00я\xc3", maxAuditOutput)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &recordingAuditRepository{}
+			s := NewAuditService(repo, redactor, slog.Default())
+			s.RecordExec(context.Background(), 1, 1, "ctr", "echo s3cr3t\x00\xff", 0, 0, tc.output)
+
+			for field, value := range map[string]string{"command": repo.audit.Command, "output": repo.audit.Output} {
+				if !utf8.ValidString(value) {
+					t.Errorf("%s is not valid UTF-8: %q", field, value)
+				}
+				if strings.Contains(value, "\x00") {
+					t.Errorf("%s contains NUL: %q", field, value)
+				}
+				if strings.Contains(value, "s3cr3t") {
+					t.Errorf("%s contains the secret: %q", field, value)
+				}
+			}
+			if len(repo.audit.Output) > maxAuditOutput {
+				t.Errorf("output is %d bytes, want at most %d", len(repo.audit.Output), maxAuditOutput)
+			}
+		})
+	}
+}
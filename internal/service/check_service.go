@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Максимальная длина вывода проверки, сохраняемого в истории
+const maxCheckOutput = 4096
+
+var ErrNoChecks = errors.New("task has no checks")
+
+type CheckService struct {
+	LabService      *LabService
+	CheckRepository interfaces.LabCheckInterface
+	Logger          *slog.Logger
+}
+
+func NewCheckService(labService *LabService, checkRepository interfaces.LabCheckInterface, logger *slog.Logger) *CheckService {
+	return &CheckService{
+		LabService:      labService,
+		CheckRepository: checkRepository,
+		Logger:          logger,
+	}
+}
+
+// RunChecks выполняет скрипты проверки задания внутри контейнера лаборатории и сохраняет попытку
+func (s *CheckService) RunChecks(ctx context.Context, labID uint, userID uint) (*model.LabCheckResult, error) {
+	lab, err := s.LabService.GetLab(ctx, labID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.LabService.GetTask(ctx, lab.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(task.Checks) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrNoChecks, task.ID)
+	}
+
+	result := &model.LabCheckResult{
+		LabID:  lab.ID,
+		TaskID: lab.TaskID,
+		UserID: userID,
+		Passed: true,
+	}
+	for _, check := range task.Checks {
+		outcome := s.runCheck(ctx, lab, userID, check)
+		result.MaxScore += outcome.Points
+		if outcome.Passed {
+			result.Score += outcome.Points
+		} else {
+			result.Passed = false
+		}
+		result.Checks = append(result.Checks, outcome)
+	}
+
+	if err := s.CheckRepository.CreateCheckResult(ctx, result); err != nil {
+		return nil, fmt.Errorf("failed to save check result: %w", err)
+	}
+	s.Logger.InfoContext(ctx, "Lab checked",
+		"lab_id", lab.ID, "score", result.Score, "max_score", result.MaxScore, "passed", result.Passed)
+	return result, nil
+}
+
+// GetCheckHistory возвращает все попытки проверки лаборатории
+func (s *CheckService) GetCheckHistory(ctx context.Context, labID uint) ([]*model.LabCheckResult, error) {
+	if _, err := s.LabService.GetLab(ctx, labID); err != nil {
+		return nil, err
+	}
+	results, err := s.CheckRepository.GetCheckResults(ctx, labID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get check results: %w", err)
+	}
+	return results, nil
+}
+
+// Выполняет один скрипт проверки и сравнивает результат с ожидаемым
+func (s *CheckService) runCheck(ctx context.Context, lab *model.Lab, userID uint, check model.TaskCheck) model.CheckOutcome {
+	outcome := model.CheckOutcome{Name: check.Name, Points: check.Points}
+	if outcome.Points == 0 {
+		outcome.Points = 1
+	}
+	containerID, err := s.LabService.ResolveContainer(lab, check.Service)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	expectedExitCode := 0
+	if check.ExpectedExitCode != nil {
+		expectedExitCode = *check.ExpectedExitCode
+	}
+	timeout := 30 * time.Second
+	if check.TimeoutSeconds > 0 {
+		timeout = time.Duration(check.TimeoutSeconds) * time.Second
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// Без этой проверки ошибка docker exec у остановленного контейнера засчитывалась бы как код скрипта
+	if err := s.LabService.CheckRunning(checkCtx, containerID); err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	output, exitCode, err := s.LabService.auditedExec(checkCtx, lab.ID, userID, containerID, check.Script)
+	outcome.ExitCode = exitCode
+	// Вывод сохраняется в истории проверок и отдаётся пользователю, поэтому секреты вырезаются до обрезки
+	redactor := s.LabService.SecretService.Redactor
+	outcome.Output = truncate(redactor.Redact(output), maxCheckOutput)
+	if err == nil && runtimeFailure(exitCode, output) {
+		err = fmt.Errorf("container runtime failed to run the script (exit code %d)", exitCode)
+	}
+	if err != nil {
+		s.Logger.WarnContext(ctx, "Check script failed to run", "check", check.Name, "error", err)
+		outcome.Error = redactor.Redact(err.Error())
+		return outcome
+	}
+	if exitCode != expectedExitCode {
+		return outcome
+	}
+
+	if check.ExpectedOutput != "" {
+		matched, err := regexp.MatchString(check.ExpectedOutput, output)
+		if err != nil {
+			outcome.Error = fmt.Sprintf("invalid expected_output pattern: %v", err)
+			return outcome
+		}
+		if !matched {
+			return outcome
+		}
+	}
+	outcome.Passed = true
+	return outcome
+}
+
+// Код завершения, который вернул сам docker exec, а не скрипт: 125 — ошибка docker. Ошибки демона
+// (контейнер остановили или удалили после проверки) завершаются кодом 1 с сообщением
+// "Error response from daemon". Коды 126 и 127 возвращает и сам скрипт, их может ожидать проверка.
+func runtimeFailure(exitCode int, output string) bool {
+	return exitCode == 125 || strings.HasPrefix(output, "Error response from daemon")
+}
+
+// Приводит вывод команды к виду, который можно сохранить в текстовой колонке: невалидные байты UTF-8
+// заменяются на U+FFFD, NUL удаляются (Postgres не принимает их в text)
+func sanitizeText(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\x00", "")
+}
+
+// Очищает строку и обрезает её не длиннее limit байт, не разрезая символ UTF-8
+func truncate(s string, limit int) string {
+	s = sanitizeText(s)
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"lab/internal/model"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrFileNotFound = errors.New("file not found in container")
+
+// Копирует tar-поток в каталог контейнера через docker cp. Такой способ не требует утилит
+// внутри образа, но каталог назначения должен существовать.
+func (s *LabService) copyToContainer(ctx context.Context, container, dir string, archive io.Reader) error {
+	cmd := exec.CommandContext(ctx, s.Runtime, "cp", "-", container+":"+dir)
+	cmd.Stdin = archive
+	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
+	output, err := runCombined(ctx, cmd)
+	if err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		if isMissingPathOutput(outputStr) {
+			return fmt.Errorf("%w: %s", ErrFileNotFound, dir)
+		}
+		return fmt.Errorf("docker cp to %s:%s: %w (output: %s)", container, dir, err, outputStr)
+	}
+	return nil
+}
+
+// Записывает один файл в контейнер от имени root
+func (s *LabService) writeFileToContainer(ctx context.Context, container, filePath string, content []byte, mode int64) error {
+	return s.writeTarFile(ctx, container, filePath, content, tar.Header{Mode: mode})
+}
+
+// Записывает один файл в контейнер; режим и владелец берутся из header
+func (s *LabService) writeTarFile(ctx context.Context, container, filePath string, content []byte, header tar.Header) error {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	header.Typeflag = tar.TypeReg
+	header.Name = path.Base(filePath)
+	header.Size = int64(len(content))
+	header.ModTime = time.Now()
+	if err := tw.WriteHeader(&header); err != nil {
+		return fmt.Errorf("write tar header: %w", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("write tar content: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("close tar: %w", err)
+	}
+	return s.copyToContainer(ctx, container, path.Dir(filePath), &archive)
+}
+
+// Кладёт флаг CTF в файл основного контейнера, если задание этого требует
+func (s *LabService) deliverFlagFile(ctx context.Context, lab *model.Lab, containerName string) error {
+	if lab.Flag == nil || lab.Flag.File == "" {
+		return nil
+	}
+	flag := s.SecretService.RegisterFlag(lab)
+	if err := s.writeFileToContainer(ctx, containerName, lab.Flag.File, []byte(flag+"\n"), 0o644); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to deliver flag file", "error", err, "lab_id", lab.ID)
+		return fmt.Errorf("failed to deliver flag file: %w", err)
+	}
+	return nil
+}
+
+// ContainerArchive — tar-поток пути в контейнере, полученный через docker cp.
+// Root — заголовок самого пути: обычный файл, каталог или ссылка.
+type ContainerArchive struct {
+	Root *tar.Header
+
+	reader *tar.Reader
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	cancel context.CancelFunc
+	call   *runtimeCall
+}
+
+// Открывает tar-поток пути в контейнере и читает заголовок корневого элемента
+func (s *LabService) openContainerArchive(ctx context.Context, container, filePath string) (*ContainerArchive, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, s.Runtime, "cp", container+":"+filePath, "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("docker cp stdout: %w", err)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
+	call := startRuntime(ctx, cmd)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		call.end(err)
+		return nil, fmt.Errorf("docker cp from %s:%s: %w", container, filePath, err)
+	}
+
+	archive := &ContainerArchive{reader: tar.NewReader(stdout), cmd: cmd, stderr: stderr, cancel: cancel, call: call}
+	root, err := archive.reader.Next()
+	if err != nil {
+		waitErr := cmd.Wait()
+		cancel()
+		call.end(waitErr)
+		output := strings.TrimSpace(stderr.String())
+		if isMissingPathOutput(output) {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+		}
+		return nil, fmt.Errorf("docker cp from %s:%s: %v (wait: %v, output: %s)", container, filePath, err, waitErr, output)
+	}
+	archive.Root = root
+	return archive, nil
+}
+
+// Next возвращает заголовок следующего элемента внутри каталога
+func (a *ContainerArchive) Next() (*tar.Header, error) {
+	return a.reader.Next()
+}
+
+// Read читает содержимое текущего элемента
+func (a *ContainerArchive) Read(p []byte) (int, error) {
+	return a.reader.Read(p)
+}
+
+// Close останавливает docker cp, если поток дочитан не до конца
+func (a *ContainerArchive) Close() error {
+	a.cancel()
+	// Прерванный нами docker cp завершается по сигналу, это не ошибка среды контейнеров
+	a.cmd.Wait()
+	a.call.end(nil)
+	return nil
+}
+
+// WriteTar перепаковывает каталог в tar, прерываясь, если содержимое превысило maxBytes
+func (a *ContainerArchive) WriteTar(w io.Writer, maxBytes int64) error {
+	tw := tar.NewWriter(w)
+	var total int64
+	for header := a.Root; ; {
+		total += header.Size
+		if maxBytes > 0 && total > maxBytes {
+			return fmt.Errorf("%w: archive exceeds %d bytes", ErrFileTooLarge, maxBytes)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, a.reader); err != nil {
+			return err
+		}
+
+		next, err := a.reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		header = next
+	}
+	return tw.Close()
+}
+
+// WriteZip перепаковывает каталог в zip, прерываясь, если содержимое превысило maxBytes
+func (a *ContainerArchive) WriteZip(w io.Writer, maxBytes int64) error {
+	zw := zip.NewWriter(w)
+	var total int64
+	for header := a.Root; ; {
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg:
+			total += header.Size
+			if maxBytes > 0 && total > maxBytes {
+				return fmt.Errorf("%w: archive exceeds %d bytes", ErrFileTooLarge, maxBytes)
+			}
+			info, err := zip.FileInfoHeader(header.FileInfo())
+			if err != nil {
+				return err
+			}
+			info.Name = header.Name
+			if header.Typeflag == tar.TypeDir && !strings.HasSuffix(info.Name, "/") {
+				info.Name += "/"
+			}
+			if header.Typeflag == tar.TypeReg {
+				info.Method = zip.Deflate
+			}
+			entry, err := zw.CreateHeader(info)
+			if err != nil {
+				return err
+			}
+			if header.Typeflag == tar.TypeReg {
+				if _, err := io.Copy(entry, a.reader); err != nil {
+					return err
+				}
+			}
+		default:
+			// Ссылки и специальные файлы в zip не переносятся
+		}
+
+		next, err := a.reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		header = next
+	}
+	return zw.Close()
+}
+
+// docker cp сообщает об отсутствующем пути по-разному в зависимости от версии
+func isMissingPathOutput(output string) bool {
+	return strings.Contains(output, "No such container:path") ||
+		strings.Contains(output, "Could not find the file") ||
+		strings.Contains(output, "no such file or directory")
+}
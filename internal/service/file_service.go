@@ -0,0 +1,204 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+)
+
+// Сколько символических ссылок разрешается в одном пути, как MAXSYMLINKS в Linux
+const maxSymlinkHops = 40
+
+var (
+	ErrPathNotAllowed = errors.New("path is outside allowed roots")
+	ErrFileTooLarge   = errors.New("file is too large")
+)
+
+// FileService переносит файлы между клиентом и контейнерами лабораторий.
+// Доступны только пути внутри AllowedRoots.
+type FileService struct {
+	LabService       *LabService
+	AllowedRoots     []string
+	MaxUploadBytes   int64
+	MaxDownloadBytes int64
+	PreviewMaxBytes  int64 // Лимит предпросмотра и редактирования текстовых файлов
+	Logger           *slog.Logger
+}
+
+func NewFileService(labService *LabService, allowedRoots []string, maxUploadBytes, maxDownloadBytes, previewMaxBytes int64, logger *slog.Logger) *FileService {
+	return &FileService{
+		LabService:       labService,
+		AllowedRoots:     allowedRoots,
+		MaxUploadBytes:   maxUploadBytes,
+		MaxDownloadBytes: maxDownloadBytes,
+		PreviewMaxBytes:  previewMaxBytes,
+		Logger:           logger,
+	}
+}
+
+// CleanPath нормализует абсолютный путь и проверяет, что он лежит внутри одного из разрешённых корней.
+// Символические ссылки здесь не учитываются: их разрешает resolve внутри контейнера.
+func (s *FileService) CleanPath(filePath string) (string, error) {
+	if !path.IsAbs(filePath) {
+		return "", fmt.Errorf("%w: path must be absolute", ErrPathNotAllowed)
+	}
+	cleaned := path.Clean(filePath)
+	for _, root := range s.AllowedRoots {
+		root = path.Clean(root)
+		if cleaned == root || strings.HasPrefix(cleaned, strings.TrimSuffix(root, "/")+"/") {
+			return cleaned, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, cleaned)
+}
+
+// UploadFile записывает содержимое в файл контейнера лаборатории
+func (s *FileService) UploadFile(ctx context.Context, labID uint, serviceName, filePath string, content io.Reader, mode int64) error {
+	container, cleaned, err := s.resolve(ctx, labID, serviceName, filePath)
+	if err != nil {
+		return err
+	}
+	if cleaned == "/" || strings.HasSuffix(filePath, "/") {
+		return fmt.Errorf("%w: upload path must be a file", ErrPathNotAllowed)
+	}
+
+	data, err := s.readUpload(content)
+	if err != nil {
+		return err
+	}
+
+	if err := s.LabService.writeFileToContainer(ctx, container, cleaned, data, mode); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to upload file", "error", err, "lab_id", labID, "path", cleaned)
+		return err
+	}
+	s.Logger.InfoContext(ctx, "File uploaded to lab", "lab_id", labID, "path", cleaned, "size", len(data))
+	return nil
+}
+
+// UploadArchive распаковывает tar-архив в каталог контейнера лаборатории
+func (s *FileService) UploadArchive(ctx context.Context, labID uint, serviceName, dir string, archive io.Reader) error {
+	container, cleaned, err := s.resolve(ctx, labID, serviceName, dir)
+	if err != nil {
+		return err
+	}
+
+	// Архив читается целиком до отправки в docker cp, чтобы превышение лимита не оставляло частично распакованных файлов
+	data, err := s.readUpload(archive)
+	if err != nil {
+		return err
+	}
+	if err := s.LabService.copyToContainer(ctx, container, cleaned, bytes.NewReader(data)); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to upload archive", "error", err, "lab_id", labID, "path", cleaned)
+		return err
+	}
+	s.Logger.InfoContext(ctx, "Archive uploaded to lab", "lab_id", labID, "path", cleaned)
+	return nil
+}
+
+// OpenDownload открывает файл или каталог контейнера для скачивания.
+// Вызывающий обязан закрыть архив.
+func (s *FileService) OpenDownload(ctx context.Context, labID uint, serviceName, filePath string) (*ContainerArchive, error) {
+	container, cleaned, err := s.resolve(ctx, labID, serviceName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.LabService.openContainerArchive(ctx, container, cleaned)
+	if err != nil {
+		return nil, err
+	}
+	if archive.Root.Size > s.MaxDownloadBytes {
+		archive.Close()
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrFileTooLarge, s.MaxDownloadBytes)
+	}
+	return archive, nil
+}
+
+// Находит контейнер лаборатории и проверяет путь: сначала как есть, затем после разрешения
+// символических ссылок, чтобы ссылка внутри разрешённого корня не вела за его пределы.
+// Возвращается разрешённый путь, дальше операция работает с ним.
+func (s *FileService) resolve(ctx context.Context, labID uint, serviceName, filePath string) (string, string, error) {
+	cleaned, err := s.CleanPath(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	lab, err := s.LabService.GetLab(ctx, labID)
+	if err != nil {
+		return "", "", err
+	}
+	container, err := s.LabService.ResolveContainer(lab, serviceName)
+	if err != nil {
+		return "", "", err
+	}
+	realPath, err := s.realPath(ctx, container, cleaned)
+	if err != nil {
+		return "", "", err
+	}
+	resolved, err := s.CleanPath(realPath)
+	if err != nil {
+		return "", "", err
+	}
+	return container, resolved, nil
+}
+
+// Разрешает символические ссылки пути по элементам, как ядро: для каждого элемента docker cp
+// отдаёт заголовок tar, у ссылки цель берётся из Linkname. Утилиты внутри образа не нужны.
+// Последний элемент пути может не существовать, например при загрузке нового файла.
+func (s *FileService) realPath(ctx context.Context, container, cleaned string) (string, error) {
+	resolved := "/"
+	remaining := strings.Split(cleaned, "/")
+	for hops := 0; len(remaining) > 0; {
+		name := remaining[0]
+		remaining = remaining[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, name)
+		archive, err := s.LabService.openContainerArchive(ctx, container, next)
+		if errors.Is(err, ErrFileNotFound) && len(remaining) == 0 {
+			return next, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		root := *archive.Root
+		archive.Close()
+		if root.Typeflag != tar.TypeSymlink {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", fmt.Errorf("%w: too many symbolic links in %s", ErrPathNotAllowed, cleaned)
+		}
+		// Абсолютная цель разрешается от корня, относительная — от каталога ссылки
+		if path.IsAbs(root.Linkname) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(root.Linkname, "/"), remaining...)
+	}
+	return resolved, nil
+}
+
+// Читает загружаемые данные, не больше MaxUploadBytes
+func (s *FileService) readUpload(content io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(content, s.MaxUploadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > s.MaxUploadBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrFileTooLarge, s.MaxUploadBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Заменяет docker: "cp container:path -" отдаёт tar пути из каталога rootfs, ссылку — как ссылку
+const fakeCopyRuntime = `#!/bin/sh
+[ "$1" = cp ] || exit 125
+p="$ROOTFS${2#*:}"
+if [ ! -e "$p" ] && [ ! -L "$p" ]; then
+	echo "Error response from daemon: Could not find the file" >&2
+	exit 1
+fi
+exec tar -C "$(dirname "$p")" -cf - "$(basename "$p")"
+`
+
+func TestRealPath(t *testing.T) {
+	rootfs := t.TempDir()
+	for _, dir := range []string{"root/work", "etc", "srv/data"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"root/etc":       "/etc",          // Абсолютная ссылка за пределы корня
+		"root/up":        "../etc",        // Относительная ссылка за пределы корня
+		"root/data":      "/srv/data",     // Разрешённая ссылка между корнями
+		"root/work/self": "../work/./",    // Ссылка на каталог внутри того же корня
+		"root/loop":      "loop",          // Бесконечная ссылка
+		"root/nested":    "/root/up/../x", // Ссылка через ссылку
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(rootfs, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runtime := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(runtime, []byte(fakeCopyRuntime), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ROOTFS", rootfs)
+
+	s := &FileService{
+		LabService:   &LabService{Runtime: runtime, Logger: slog.Default()},
+		AllowedRoots: []string{"/root", "/srv/data"},
+		Logger:       slog.Default(),
+	}
+	for _, tc := range []struct {
+		path string
+		want string
+		err  error
+	}{
+		{path: "/root/work", want: "/root/work"},
+		{path: "/root/work/new.txt", want: "/root/work/new.txt"},
+		{path: "/root/work/self/new.txt", want: "/root/work/new.txt"},
+		{path: "/root/data/file", want: "/srv/data/file"},
+		{path: "/root/etc/passwd", err: ErrPathNotAllowed},
+		{path: "/root/up/passwd", err: ErrPathNotAllowed},
+		{path: "/root/nested", err: ErrPathNotAllowed},
+		{path: "/root/loop", err: ErrPathNotAllowed},
+		{path: "/root/missing/file", err: ErrFileNotFound},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			got, err := s.realPath(context.Background(), "lab", tc.path)
+			if err == nil {
+				got, err = s.CleanPath(got)
+			}
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("got %q, %v; want error %v", got, err, tc.err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("got %q, %v; want %q", got, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoFlag          = errors.New("lab has no flag")
+	ErrTooManyAttempts = errors.New("too many flag attempts")
+)
+
+type FlagService struct {
+	LabService           *LabService
+	SubmissionRepository interfaces.FlagSubmissionInterface
+	Logger               *slog.Logger
+
+	limiter *attemptLimiter
+}
+
+func NewFlagService(labService *LabService, submissionRepository interfaces.FlagSubmissionInterface, attemptsPerMinute int, logger *slog.Logger) *FlagService {
+	return &FlagService{
+		LabService:           labService,
+		SubmissionRepository: submissionRepository,
+		Logger:               logger,
+		limiter:              newAttemptLimiter(attemptsPerMinute, time.Minute),
+	}
+}
+
+// FlagResult — ответ на попытку сдачи флага
+type FlagResult struct {
+	Correct    bool          `json:"correct"`
+	RetryAfter time.Duration `json:"-"` // Заполняется вместе с ErrTooManyAttempts
+}
+
+// SubmitFlag проверяет флаг, отправленный из лаборатории, и сохраняет попытку.
+// Если флаг принадлежит лаборатории другого владельца того же задания, попытка помечается как подозрение на обмен флагами.
+func (s *FlagService) SubmitFlag(ctx context.Context, labID uint, userID uint, submitted string) (*FlagResult, error) {
+	lab, err := s.LabService.GetLab(ctx, labID)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Flag == nil {
+		return nil, ErrNoFlag
+	}
+	if ok, retryAfter := s.limiter.allow(fmt.Sprintf("%d:%d", labID, userID)); !ok {
+		s.Logger.WarnContext(ctx, "Flag submission rate limited", "lab_id", labID, "user_id", userID)
+		return &FlagResult{RetryAfter: retryAfter}, ErrTooManyAttempts
+	}
+
+	submitted = strings.TrimSpace(submitted)
+	digest := sha256.Sum256([]byte(submitted))
+	submission := &model.FlagSubmission{
+		LabID:    lab.ID,
+		TaskID:   lab.TaskID,
+		UserID:   userID,
+		FlagHash: hex.EncodeToString(digest[:]),
+		Correct:  flagEqual(submitted, s.LabService.SecretService.LabFlag(lab)),
+	}
+
+	if !submission.Correct {
+		source, err := s.findFlagOwner(ctx, lab, submitted)
+		if err != nil {
+			return nil, err
+		}
+		if source != nil && source.OwnerID != lab.OwnerID {
+			submission.SuspectedSharing = true
+			submission.SourceLabID = source.ID
+			submission.SourceOwnerID = source.OwnerID
+			s.Logger.WarnContext(ctx, "Suspected flag sharing",
+				"lab_id", lab.ID, "owner_id", lab.OwnerID,
+				"source_lab_id", source.ID, "source_owner_id", source.OwnerID)
+		}
+	}
+
+	if err := s.SubmissionRepository.CreateSubmission(ctx, submission); err != nil {
+		return nil, fmt.Errorf("failed to save flag submission: %w", err)
+	}
+	return &FlagResult{Correct: submission.Correct}, nil
+}
+
+// GetSubmissions возвращает историю попыток сдачи флага в лаборатории
+func (s *FlagService) GetSubmissions(ctx context.Context, labID uint) ([]*model.FlagSubmission, error) {
+	if _, err := s.LabService.GetLab(ctx, labID); err != nil {
+		return nil, err
+	}
+	submissions, err := s.SubmissionRepository.GetSubmissions(ctx, labID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flag submissions: %w", err)
+	}
+	return submissions, nil
+}
+
+// Ищет среди лабораторий того же задания ту, чей флаг был отправлен
+func (s *FlagService) findFlagOwner(ctx context.Context, lab *model.Lab, submitted string) (*model.Lab, error) {
+	labs, err := s.LabService.LabRepository.GetLabsByTask(ctx, lab.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labs of task %d: %w", lab.TaskID, err)
+	}
+	for _, other := range labs {
+		if other.ID == lab.ID || other.Flag == nil {
+			continue
+		}
+		if flagEqual(submitted, s.LabService.SecretService.LabFlag(other)) {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
+
+func flagEqual(submitted, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) == 1
+}
+
+// attemptLimiter ограничивает число попыток по ключу в скользящем окне
+type attemptLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	attempts  map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:    limit,
+		window:   window,
+		attempts: make(map[string][]time.Time),
+	}
+}
+
+// Регистрирует попытку и сообщает, разрешена ли она; иначе возвращает время до следующей разрешённой попытки
+func (l *attemptLimiter) allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+	recent := l.attempts[key][:0]
+	for _, at := range l.attempts[key] {
+		if now.Sub(at) < l.window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.attempts[key] = recent
+		return false, l.window - now.Sub(recent[0])
+	}
+	l.attempts[key] = append(recent, now)
+	return true, 0
+}
+
+// Раз в окно удаляет ключи, все попытки которых вышли из окна, чтобы карта не росла
+// с каждой новой парой лаборатории и пользователя
+func (l *attemptLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, attempts := range l.attempts {
+		if len(attempts) == 0 || now.Sub(attempts[len(attempts)-1]) >= l.window {
+			delete(l.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Не больше стольких элементов возвращается в одном листинге каталога
+const maxDirEntries = 5000
+
+var (
+	ErrNotDirectory = errors.New("path is not a directory")
+	ErrNotTextFile  = errors.New("file is not a text file")
+
+	// В контейнере нет find (или sh), либо find не поддерживает -printf
+	errFindUnavailable = errors.New("find with -printf is unavailable")
+)
+
+// DirEntry — элемент листинга каталога контейнера
+type DirEntry struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"` // file, dir, symlink или other
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"` // Права в восьмеричном виде, например 0644
+	ModTime    time.Time `json:"mtime"`
+	LinkTarget string    `json:"link_target,omitempty"`
+}
+
+// DirListing — содержимое каталога контейнера
+type DirListing struct {
+	Path      string     `json:"path"`
+	Entries   []DirEntry `json:"entries"`
+	Truncated bool       `json:"truncated"`
+}
+
+// TextFile — содержимое текстового файла контейнера для предпросмотра
+type TextFile struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Mode      string    `json:"mode"`
+	ModTime   time.Time `json:"mtime"`
+	Content   string    `json:"content"`
+	Truncated bool      `json:"truncated"`
+}
+
+// ListDir возвращает содержимое каталога контейнера. Сначала используется find внутри контейнера,
+// а если его нет или он не поддерживает -printf (минимальные образы), каталог читается через docker cp.
+func (s *FileService) ListDir(ctx context.Context, labID, userID uint, serviceName, dir string) (*DirListing, error) {
+	container, cleaned, err := s.resolve(ctx, labID, serviceName, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	listing, err := s.listDirWithFind(ctx, labID, userID, container, cleaned)
+	if errors.Is(err, errFindUnavailable) {
+		s.Logger.DebugContext(ctx, "find is unavailable, listing directory through docker cp", "error", err, "path", cleaned)
+		listing, err = s.listDirWithArchive(ctx, container, cleaned)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(listing.Entries, func(i, j int) bool {
+		if (listing.Entries[i].Type == "dir") != (listing.Entries[j].Type == "dir") {
+			return listing.Entries[i].Type == "dir"
+		}
+		return listing.Entries[i].Name < listing.Entries[j].Name
+	})
+	return listing, nil
+}
+
+// Листинг через GNU find: по одной строке на элемент, поля разделены табуляцией, строки — нулевым байтом
+func (s *FileService) listDirWithFind(ctx context.Context, labID, userID uint, container, dir string) (*DirListing, error) {
+	script := fmt.Sprintf(`test -d %s || { echo "not a directory"; exit 20; }; find %s -mindepth 1 -maxdepth 1 -printf '%%y\t%%s\t%%m\t%%T@\t%%l\t%%f\0'`,
+		shellQuote(dir), shellQuote(dir))
+	output, exitCode, err := s.LabService.auditedExec(ctx, labID, userID, container, script)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode == 20 {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, dir)
+	}
+	// 127 — не найдена команда; find из busybox и BSD сообщает о неизвестном -printf
+	if exitCode == 127 || (exitCode != 0 && strings.Contains(output, "-printf")) {
+		return nil, fmt.Errorf("%w: exit code %d: %s", errFindUnavailable, exitCode, output)
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("find exited with code %d: %s", exitCode, output)
+	}
+
+	listing := &DirListing{Path: dir, Entries: []DirEntry{}}
+	for _, line := range strings.Split(output, "\x00") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 6)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected find output %q", line)
+		}
+		if len(listing.Entries) == maxDirEntries {
+			listing.Truncated = true
+			break
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		mode, _ := strconv.ParseUint(fields[2], 8, 32)
+		seconds, _ := strconv.ParseFloat(fields[3], 64)
+		listing.Entries = append(listing.Entries, DirEntry{
+			Name:       fields[5],
+			Type:       findType(fields[0]),
+			Size:       size,
+			Mode:       fmt.Sprintf("%04o", mode),
+			ModTime:    time.Unix(0, int64(seconds*float64(time.Second))).UTC(),
+			LinkTarget: fields[4],
+		})
+	}
+	return listing, nil
+}
+
+// Листинг через docker cp: читаются только заголовки tar-потока, вложенные каталоги пропускаются
+func (s *FileService) listDirWithArchive(ctx context.Context, container, dir string) (*DirListing, error) {
+	archive, err := s.LabService.openContainerArchive(ctx, container, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	if archive.Root.Typeflag != tar.TypeDir {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, dir)
+	}
+
+	rootName := strings.TrimSuffix(archive.Root.Name, "/")
+	listing := &DirListing{Path: dir, Entries: []DirEntry{}}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read directory archive: %w", err)
+		}
+		name := strings.TrimPrefix(strings.TrimSuffix(header.Name, "/"), rootName+"/")
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		if len(listing.Entries) == maxDirEntries {
+			listing.Truncated = true
+			break
+		}
+		listing.Entries = append(listing.Entries, DirEntry{
+			Name:       name,
+			Type:       tarType(header.Typeflag),
+			Size:       header.Size,
+			Mode:       fmt.Sprintf("%04o", fs.FileMode(header.Mode).Perm()),
+			ModTime:    header.ModTime.UTC(),
+			LinkTarget: header.Linkname,
+		})
+	}
+	return listing, nil
+}
+
+// ReadTextFile возвращает начало текстового файла, не больше PreviewMaxBytes
+func (s *FileService) ReadTextFile(ctx context.Context, labID uint, serviceName, filePath string) (*TextFile, error) {
+	container, cleaned, err := s.resolve(ctx, labID, serviceName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.LabService.openContainerArchive(ctx, container, cleaned)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	if archive.Root.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%w: %s is not a regular file", ErrNotTextFile, cleaned)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(archive, s.PreviewMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	truncated := archive.Root.Size > int64(len(content))
+	if truncated {
+		// Не обрываем последний многобайтовый символ
+		for i := 0; i < utf8.UTFMax && len(content) > 0 && !utf8.Valid(content); i++ {
+			content = content[:len(content)-1]
+		}
+	}
+	if !isText(content) {
+		return nil, fmt.Errorf("%w: %s", ErrNotTextFile, cleaned)
+	}
+
+	return &TextFile{
+		Path:      cleaned,
+		Size:      archive.Root.Size,
+		Mode:      fmt.Sprintf("%04o", fs.FileMode(archive.Root.Mode).Perm()),
+		ModTime:   archive.Root.ModTime.UTC(),
+		Content:   string(content),
+		Truncated: truncated,
+	}, nil
+}
+
+// WriteTextFile сохраняет небольшой текстовый файл. Права и владелец существующего файла сохраняются.
+func (s *FileService) WriteTextFile(ctx context.Context, labID uint, serviceName, filePath, content string) error {
+	container, cleaned, err := s.resolve(ctx, labID, serviceName, filePath)
+	if err != nil {
+		return err
+	}
+	if int64(len(content)) > s.PreviewMaxBytes {
+		return fmt.Errorf("%w: limit is %d bytes", ErrFileTooLarge, s.PreviewMaxBytes)
+	}
+	if !isText([]byte(content)) {
+		return fmt.Errorf("%w: content is not valid text", ErrNotTextFile)
+	}
+
+	header := tar.Header{Mode: 0o644}
+	archive, err := s.LabService.openContainerArchive(ctx, container, cleaned)
+	switch {
+	case err == nil:
+		existing := *archive.Root
+		archive.Close()
+		if existing.Typeflag != tar.TypeReg {
+			return fmt.Errorf("%w: %s is not a regular file", ErrNotTextFile, cleaned)
+		}
+		if existing.Size > s.PreviewMaxBytes {
+			return fmt.Errorf("%w: existing file exceeds %d bytes", ErrFileTooLarge, s.PreviewMaxBytes)
+		}
+		header.Mode = existing.Mode
+		header.Uid, header.Gid = existing.Uid, existing.Gid
+		header.Uname, header.Gname = existing.Uname, existing.Gname
+	case errors.Is(err, ErrFileNotFound):
+	default:
+		return err
+	}
+
+	if err := s.LabService.writeTarFile(ctx, container, cleaned, []byte(content), header); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to save text file", "error", err, "lab_id", labID, "path", cleaned)
+		return err
+	}
+	s.Logger.InfoContext(ctx, "Text file saved in lab", "lab_id", labID, "path", cleaned, "size", len(content))
+	return nil
+}
+
+// Текст — корректный UTF-8 без нулевых байтов
+func isText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
+
+func findType(kind string) string {
+	switch kind {
+	case "f":
+		return "file"
+	case "d":
+		return "dir"
+	case "l":
+		return "symlink"
+	default:
+		return "other"
+	}
+}
+
+func tarType(flag byte) string {
+	switch flag {
+	case tar.TypeReg:
+		return "file"
+	case tar.TypeDir:
+		return "dir"
+	case tar.TypeSymlink:
+		return "symlink"
+	default:
+		return "other"
+	}
+}
+
+// Экранирует строку для подстановки в sh -c
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Состояния в отчёте о готовности
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // Не работает некритичный компонент
+	HealthFail     = "fail"
+)
+
+// HealthCheck — проверка одного компонента. Check возвращает подробности для отчёта.
+type HealthCheck struct {
+	Name     string
+	Critical bool // Сервис не готов, если критичная проверка не прошла
+	Check    func(ctx context.Context) (map[string]any, error)
+}
+
+// ComponentHealth — результат проверки компонента
+type ComponentHealth struct {
+	Status    string         `json:"status"`
+	Critical  bool           `json:"critical"`
+	LatencyMs int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// HealthReport — отчёт о готовности сервиса
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// Ready сообщает, можно ли направлять запросы в сервис
+func (r *HealthReport) Ready() bool {
+	return r.Status != HealthFail
+}
+
+// HealthService выполняет проверки зависимостей для readiness-проб
+type HealthService struct {
+	Checks  []HealthCheck
+	Timeout time.Duration // Время на каждую проверку
+	Logger  *slog.Logger
+}
+
+func NewHealthService(checks []HealthCheck, timeout time.Duration, logger *slog.Logger) *HealthService {
+	return &HealthService{
+		Checks:  checks,
+		Timeout: timeout,
+		Logger:  logger,
+	}
+}
+
+// Check параллельно выполняет все проверки и собирает отчёт
+func (s *HealthService) Check(ctx context.Context) *HealthReport {
+	report := &HealthReport{Status: HealthOK, Components: make(map[string]ComponentHealth, len(s.Checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range s.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check.Check(checkCtx)
+			component := ComponentHealth{
+				Status:    HealthOK,
+				Critical:  check.Critical,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Details:   details,
+			}
+			if err != nil {
+				component.Status = HealthFail
+				component.Error = err.Error()
+				s.Logger.WarnContext(ctx, "Health check failed", "component", check.Name, "error", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.Name] = component
+			switch {
+			case err == nil:
+			case check.Critical:
+				report.Status = HealthFail
+			case report.Status == HealthOK:
+				report.Status = HealthDegraded
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// DatabaseCheck проверяет соединение с базой данных
+func DatabaseCheck(ping func(ctx context.Context) error) HealthCheck {
+	return HealthCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) (map[string]any, error) {
+			return nil, ping(ctx)
+		},
+	}
+}
+
+// RuntimeCheck проверяет, что демон среды контейнеров отвечает
+func (s *LabService) RuntimeCheck() HealthCheck {
+	return HealthCheck{
+		Name:     "runtime",
+		Critical: true,
+		Check: func(ctx context.Context) (map[string]any, error) {
+			version, err := s.docker(ctx, "version", "--format", "{{.Server.Version}}")
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"server_version": version}, nil
+		},
+	}
+}
+
+// TaskServiceCheck проверяет, что сервис заданий принимает соединения. Любой HTTP-ответ считается успехом.
+func (s *LabService) TaskServiceCheck(critical bool) HealthCheck {
+	return HealthCheck{
+		Name:     "task_service",
+		Critical: critical,
+		Check: func(ctx context.Context) (map[string]any, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.TaskServiceURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			return map[string]any{"url": s.TaskServiceURL, "status_code": resp.StatusCode}, nil
+		},
+	}
+}
+
+// PortCapacityCheck проверяет, что в диапазоне осталось не меньше minFree свободных портов
+func PortCapacityCheck(ports *PortAllocator, minFree int) HealthCheck {
+	return HealthCheck{
+		Name:     "ports",
+		Critical: true,
+		Check: func(ctx context.Context) (map[string]any, error) {
+			used, total := ports.Usage()
+			details := map[string]any{"used": used, "total": total, "free": total - used}
+			if total-used < minFree {
+				return details, fmt.Errorf("%d free ports left, need at least %d", total-used, minFree)
+			}
+			return details, nil
+		},
+	}
+}
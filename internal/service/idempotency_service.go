@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gorm.io/gorm"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength   = 255
+	idempotencyAbandonedAfter = 30 * time.Minute // Запрос в состоянии pending дольше этого считается прерванным
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was used with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+)
+
+// IdempotencyService хранит ответы на запросы с заголовком Idempotency-Key, чтобы повтор
+// запроса с тем же ключом получил сохранённый ответ, а не выполнил операцию ещё раз.
+type IdempotencyService struct {
+	Repository interfaces.IdempotencyInterface
+	TTL        time.Duration // Сколько хранится ответ
+	Logger     *slog.Logger
+}
+
+func NewIdempotencyService(repository interfaces.IdempotencyInterface, ttl time.Duration, logger *slog.Logger) *IdempotencyService {
+	return &IdempotencyService{
+		Repository: repository,
+		TTL:        ttl,
+		Logger:     logger,
+	}
+}
+
+// Begin регистрирует запрос пользователя с ключом key. Если запрос с этим ключом уже выполнен,
+// возвращается запись с сохранённым ответом и replay = true. Иначе создаётся запись в состоянии
+// pending, которую после обработки нужно передать в Complete или Abandon.
+// Тот же ключ с другим методом, путём или телом — ErrIdempotencyKeyReused,
+// ключ, запрос по которому ещё выполняется, — ErrIdempotencyInProgress.
+func (s *IdempotencyService) Begin(ctx context.Context, userID uint, key, method, path string, body []byte) (record *model.IdempotencyRecord, replay bool, err error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, false, ErrInvalidIdempotencyKey
+	}
+	hash := requestHash(method, path, body)
+	// Вторая попытка нужна, если мешающая запись оказалась устаревшей и была удалена
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now()
+		record = &model.IdempotencyRecord{
+			UserID:         userID,
+			IdempotencyKey: key,
+			Method:         method,
+			Path:           path,
+			RequestHash:    hash,
+			Status:         model.IdempotencyPending,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(s.TTL),
+		}
+		created, err := s.Repository.CreateRecord(ctx, record)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			return record, false, nil
+		}
+
+		existing, err := s.Repository.GetRecord(ctx, userID, key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue // Запись успели удалить между вставкой и чтением
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		abandoned := existing.Status == model.IdempotencyPending && now.Sub(existing.CreatedAt) > idempotencyAbandonedAfter
+		if now.After(existing.ExpiresAt) || abandoned {
+			if err := s.Repository.DeleteRecord(ctx, existing.ID); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if existing.RequestHash != hash {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if existing.Status == model.IdempotencyPending {
+			return nil, false, ErrIdempotencyInProgress
+		}
+		return existing, true, nil
+	}
+	return nil, false, ErrIdempotencyInProgress
+}
+
+// Complete сохраняет ответ на запрос для последующих повторов
+func (s *IdempotencyService) Complete(ctx context.Context, record *model.IdempotencyRecord, code int, contentType string, body []byte) error {
+	record.Status = model.IdempotencyCompleted
+	record.ResponseCode = code
+	record.ResponseType = contentType
+	record.ResponseBody = body
+	return s.Repository.UpdateRecord(ctx, record)
+}
+
+// Abandon освобождает ключ, если ответ сохранять не нужно (ошибка сервера):
+// клиент может повторить запрос с тем же ключом.
+func (s *IdempotencyService) Abandon(ctx context.Context, record *model.IdempotencyRecord) error {
+	return s.Repository.DeleteRecord(ctx, record.ID)
+}
+
+// RunCleanupWorker периодически удаляет записи с истёкшим сроком хранения, пока не отменён ctx
+func (s *IdempotencyService) RunCleanupWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.Repository.DeleteExpiredRecords(context.WithoutCancel(ctx), time.Now())
+			if err != nil {
+				s.Logger.ErrorContext(ctx, "Idempotency records cleanup failed", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				s.Logger.InfoContext(ctx, "Expired idempotency records deleted", "count", deleted)
+			}
+		}
+	}
+}
+
+// Хеш запроса, с которым сравниваются повторы с тем же ключом
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
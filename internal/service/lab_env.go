@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"lab/internal/model"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	envNamePattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	envTemplatePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]+)\s*\}\}`)
+)
+
+// RegisterSecrets регистрирует в Redactor секреты и флаги уже созданных лабораторий, чтобы после
+// перезапуска сервиса они вырезались из логов, журнала команд и записей терминала
+func (s *LabService) RegisterSecrets(ctx context.Context) error {
+	labs, err := s.LabRepository.GetAllLabs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load labs: %w", err)
+	}
+	for _, lab := range labs {
+		if lab.Status == model.LabStatusTrashed {
+			continue
+		}
+		// Лаборатория с неразборчивыми секретами не должна мешать запуску остальных
+		if _, err := s.SecretService.GetSecrets(ctx, lab.ID); err != nil {
+			s.Logger.ErrorContext(ctx, "Failed to register lab secrets", "error", err, "lab_id", lab.ID)
+		}
+		s.SecretService.RegisterFlag(lab)
+	}
+	return nil
+}
+
+// Собирает переменные окружения контейнера: подставляет значения в шаблоны
+// и, для основного контейнера, добавляет секреты лаборатории под их именами
+func (s *LabService) labEnv(ctx context.Context, lab *model.Lab, templates map[string]string, withSecrets bool) (map[string]string, error) {
+	secrets, err := s.SecretService.GetSecrets(ctx, lab.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{
+		"lab.id":  strconv.FormatUint(uint64(lab.ID), 10),
+		"user.id": strconv.FormatUint(uint64(lab.OwnerID), 10),
+		"task.id": strconv.FormatUint(uint64(lab.TaskID), 10),
+	}
+	for name, value := range secrets {
+		vars["secret."+name] = value
+	}
+	flag := s.SecretService.RegisterFlag(lab)
+	if flag != "" {
+		vars["lab.flag"] = flag
+	}
+
+	env := make(map[string]string, len(templates)+len(secrets)+1)
+	if withSecrets {
+		for name, value := range secrets {
+			env[name] = value
+		}
+		if flag != "" {
+			if lab.Flag.Env != "" {
+				env[lab.Flag.Env] = flag
+			} else if lab.Flag.File == "" {
+				env["FLAG"] = flag
+			}
+		}
+	}
+	for key, template := range templates {
+		value, err := renderTemplate(template, vars)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", key, err)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// Заменяет плейсхолдеры {{name}} значениями из vars. Неизвестный плейсхолдер — ошибка.
+func renderTemplate(template string, vars map[string]string) (string, error) {
+	var unknown string
+	rendered := envTemplatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := envTemplatePattern.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok && unknown == "" {
+			unknown = name
+		}
+		return value
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown template variable %q", unknown)
+	}
+	return rendered, nil
+}
+
+// Записывает переменные окружения во временный файл для docker run --env-file,
+// чтобы значения не попадали в аргументы процесса и в логи команд.
+// Пустой путь означает, что переменных нет.
+func writeEnvFile(env map[string]string) (string, error) {
+	if len(env) == 0 {
+		return "", nil
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var content strings.Builder
+	for _, key := range keys {
+		if strings.ContainsAny(env[key], "\r\n") {
+			return "", fmt.Errorf("env %s contains a line break", key)
+		}
+		content.WriteString(key + "=" + env[key] + "\n")
+	}
+
+	file, err := os.CreateTemp("", "lab-env-*")
+	if err != nil {
+		return "", fmt.Errorf("create env file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content.String()); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("write env file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// Проверяет имена переменных окружения, определения секретов и флага задания
+func validateEnv(env map[string]string, secrets []model.SecretDefinition, flag *model.FlagDefinition) error {
+	if flag != nil {
+		if flag.Env != "" && !envNamePattern.MatchString(flag.Env) {
+			return fmt.Errorf("%w: invalid flag env name %q", ErrInvalidTask, flag.Env)
+		}
+		if flag.File != "" && (!path.IsAbs(flag.File) || strings.HasSuffix(flag.File, "/")) {
+			return fmt.Errorf("%w: flag file must be an absolute file path", ErrInvalidTask)
+		}
+		if strings.ContainsAny(flag.Prefix, "{}\r\n") {
+			return fmt.Errorf("%w: invalid flag prefix %q", ErrInvalidTask, flag.Prefix)
+		}
+	}
+	for key := range env {
+		if !envNamePattern.MatchString(key) {
+			return fmt.Errorf("%w: invalid env name %q", ErrInvalidTask, key)
+		}
+	}
+	names := make(map[string]bool, len(secrets))
+	for _, secret := range secrets {
+		if !envNamePattern.MatchString(secret.Name) {
+			return fmt.Errorf("%w: invalid secret name %q", ErrInvalidTask, secret.Name)
+		}
+		if names[secret.Name] {
+			return fmt.Errorf("%w: duplicate secret %q", ErrInvalidTask, secret.Name)
+		}
+		names[secret.Name] = true
+		if secret.Length < 0 || secret.Length > maxSecretLength {
+			return fmt.Errorf("%w: secret %q has invalid length %d", ErrInvalidTask, secret.Name, secret.Length)
+		}
+		switch secret.Format {
+		case "", SecretFormatHex, SecretFormatAlnum:
+		default:
+			return fmt.Errorf("%w: secret %q has unknown format %q", ErrInvalidTask, secret.Name, secret.Format)
+		}
+	}
+	return nil
+}
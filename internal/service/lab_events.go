@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lab/internal/model"
+	"time"
+)
+
+var ErrLabExpired = errors.New("lab has expired")
+
+// Публикует событие жизненного цикла лаборатории в шину
+func (s *LabService) publish(ctx context.Context, eventType string, lab *model.Lab, data map[string]any) {
+	event := s.Events.Publish(model.LabEvent{
+		Type:    eventType,
+		LabID:   lab.ID,
+		OwnerID: lab.OwnerID,
+		TaskID:  lab.TaskID,
+		Data:    data,
+	})
+	s.Logger.DebugContext(ctx, "Lab event published", "event_id", event.ID, "type", eventType, "lab_id", lab.ID)
+}
+
+// Публикует событие об ошибке операции над лабораторией
+func (s *LabService) publishFailure(ctx context.Context, lab *model.Lab, operation string, err error) {
+	s.publish(ctx, model.EventLabFailed, lab, map[string]any{"operation": operation, "error": err.Error()})
+}
+
+// Сохраняет новое состояние лаборатории. Ошибка только логируется: состояние контейнеров
+// уже изменилось, и откатывать операцию из-за записи статуса не нужно.
+func (s *LabService) setStatus(ctx context.Context, lab *model.Lab, status string) {
+	lab.Status = status
+	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to save lab status", "error", err, "lab_id", lab.ID, "status", status)
+	}
+}
+
+// ExpireLabs останавливает лаборатории с истёкшим сроком жизни
+func (s *LabService) ExpireLabs(ctx context.Context) error {
+	labs, err := s.LabRepository.GetExpiredLabs(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to get expired labs: %w", err)
+	}
+	for _, lab := range labs {
+		s.expireLab(ctx, lab.ID)
+	}
+	return nil
+}
+
+// Останавливает одну истёкшую лабораторию. Занятая лаборатория пропускается
+// и будет остановлена при следующем проходе.
+func (s *LabService) expireLab(ctx context.Context, labID uint) {
+	_, unlock, err := s.Locks.Lock(ctx, labID, "expire", 0)
+	if err != nil {
+		s.Logger.InfoContext(ctx, "Skipping expiry of busy lab", "lab_id", labID, "error", err)
+		return
+	}
+	defer unlock()
+
+	// Срок могли продлить, а лабораторию — удалить, пока выполнялась другая операция
+	lab, err := s.LabRepository.GetLab(ctx, int(labID))
+	if err != nil {
+		s.Logger.WarnContext(ctx, "Failed to reload expired lab", "error", err, "lab_id", labID)
+		return
+	}
+	if lab.ExpiresAt == nil || lab.ExpiresAt.After(time.Now()) ||
+		lab.Status == model.LabStatusExpired || lab.Status == model.LabStatusTrashed {
+		return
+	}
+
+	if err := s.stopContainers(ctx, lab); err != nil {
+		// Контейнер мог быть уже удалён вручную, лаборатория всё равно считается истёкшей
+		s.Logger.WarnContext(ctx, "Failed to stop expired lab", "error", err, "lab_id", lab.ID)
+	}
+	s.setStatus(ctx, lab, model.LabStatusExpired)
+	s.publish(ctx, model.EventLabExpired, lab, map[string]any{"expires_at": lab.ExpiresAt})
+	s.Logger.InfoContext(ctx, "Lab expired", "lab_id", lab.ID, "expires_at", lab.ExpiresAt)
+}
+
+// RunExpiryWorker периодически вызывает ExpireLabs, пока не отменён ctx.
+// Отмена ctx не прерывает уже начатый проход: контейнеры не остаются остановленными без смены статуса.
+func (s *LabService) RunExpiryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ExpireLabs(context.WithoutCancel(ctx)); err != nil {
+				s.Logger.ErrorContext(ctx, "Lab expiry failed", "error", err)
+			}
+		}
+	}
+}
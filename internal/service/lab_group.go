@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lab/internal/model"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrServiceNotFound = errors.New("lab service not found")
+
+// ServiceStartResult описывает запуск одного сервиса лаборатории из нескольких контейнеров
+type ServiceStartResult struct {
+	Service     string `json:"service"`
+	ContainerID string `json:"container_id"`
+	AccessURL   string `json:"access_url,omitempty"`
+	Mode        string `json:"mode"`
+	Image       string `json:"image,omitempty"`
+}
+
+// ResolveContainer возвращает ID контейнера сервиса лаборатории.
+// Пустое имя сервиса означает основной контейнер.
+func (s *LabService) ResolveContainer(lab *model.Lab, serviceName string) (string, error) {
+	if serviceName == "" {
+		return lab.ContainerID, nil
+	}
+	for _, container := range lab.Containers {
+		if container.ServiceName == serviceName {
+			return container.ContainerID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q in lab %d", ErrServiceNotFound, serviceName, lab.ID)
+}
+
+// Выполняет docker с указанными аргументами и возвращает очищенный от пробелов вывод
+func (s *LabService) docker(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, s.Runtime, args...)
+	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
+	output, err := runCombined(ctx, cmd)
+	outputStr := strings.TrimSpace(string(output))
+	if err != nil {
+		return outputStr, fmt.Errorf("docker %s: %w (output: %s)", args[0], err, outputStr)
+	}
+	return outputStr, nil
+}
+
+// Создаёт сеть и контейнеры всех сервисов шаблона. Контейнеры сохраняются в lab.Containers
+// в порядке зависимостей, в этом же порядке их запускают, а останавливают в обратном.
+func (s *LabService) createLabGroup(ctx context.Context, lab *model.Lab, template *model.LabTemplate) error {
+	services, err := orderServices(template.Services)
+	if err != nil {
+		return err
+	}
+
+	baseName := fmt.Sprintf("lab_%d_%s", lab.TaskID, time.Now().Format("20060102_150405_999"))
+	lab.ContainerName = baseName + "_" + primaryService(services)
+	lab.TemplateID = template.ID
+	lab.NetworkName = baseName + "_net"
+	if _, err := s.docker(ctx, "network", "create", lab.NetworkName); err != nil {
+		s.Logger.ErrorContext(ctx, "Error while creating lab network", "error", err, "network", lab.NetworkName)
+		return fmt.Errorf("failed to create network %s: %w", lab.NetworkName, err)
+	}
+
+	for _, svc := range services {
+		s.Limits.applyService(&svc)
+		container := model.LabContainer{
+			ServiceName:   svc.Name,
+			ContainerName: baseName + "_" + svc.Name,
+			Image:         svc.Image,
+			TerminalType:  svc.TerminalType,
+			Ports:         svc.Ports,
+			Env:           svc.Env,
+			DependsOn:     svc.DependsOn,
+			CPULimit:      svc.CPULimit,
+			MemoryLimit:   svc.MemoryLimit,
+		}
+		if err := s.runLabContainer(ctx, lab, &container, svc.Image); err != nil {
+			s.Logger.ErrorContext(ctx, "Error while creating service container", "error", err, "service", svc.Name)
+			lab.Containers = append(lab.Containers, container)
+			s.removeLabGroup(ctx, lab)
+			return err
+		}
+		lab.Containers = append(lab.Containers, container)
+	}
+
+	s.setPrimaryContainer(lab)
+	return nil
+}
+
+// Запускает контейнер сервиса в сети лаборатории и заполняет его ID и адрес терминала
+func (s *LabService) runLabContainer(ctx context.Context, lab *model.Lab, container *model.LabContainer, image string) error {
+	// Секреты под своими именами получает только основной контейнер, остальные — через шаблоны
+	env, err := s.labEnv(ctx, lab, container.Env, container.ContainerName == lab.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w: service %s: %v", ErrInvalidTask, container.ServiceName, err)
+	}
+	envFile, err := writeEnvFile(env)
+	if err != nil {
+		return err
+	}
+	if envFile != "" {
+		defer os.Remove(envFile)
+	}
+
+	args := []string{
+		"run", "-dit",
+		"--name", container.ContainerName,
+		"--network", lab.NetworkName,
+		"--network-alias", container.ServiceName,
+		"--hostname", container.ServiceName,
+	}
+
+	// Прежний контейнер сервиса удалён, его порт больше не нужен
+	s.Ports.Release(container.HostPort)
+	container.HostPort = 0
+	hostPort := 0
+	// Записываемый терминал не публикуется, как и у лаборатории из одного контейнера
+	if container.TerminalType != "" && !lab.RecordTerminal {
+		freePort, err := s.Ports.Allocate()
+		if err != nil {
+			return fmt.Errorf("failed to get free port: %w", err)
+		}
+		hostPort = freePort
+		args = append(args, "-p", fmt.Sprintf("%d:%d", hostPort, model.TerminalPort(container.TerminalType)))
+	}
+	for _, port := range container.Ports {
+		args = append(args, "-p", strconv.Itoa(port))
+	}
+	if envFile != "" {
+		args = append(args, "--env-file", envFile)
+	}
+	if container.CPULimit != "" {
+		args = append(args, "--cpus", container.CPULimit)
+	}
+	if container.MemoryLimit != "" {
+		args = append(args, "--memory", container.MemoryLimit)
+	}
+	if lab.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(lab.PidsLimit))
+	}
+	args = append(args, image)
+	if container.TerminalType == model.TerminalWetty {
+		args = append(args, "--base", "/wetty", "--reverse-proxy")
+	}
+
+	containerID, err := s.docker(ctx, args...)
+	if err != nil {
+		s.Ports.Release(hostPort)
+		return fmt.Errorf("error while creating container %s: %w", container.ContainerName, err)
+	}
+	container.ContainerID = containerID
+	container.HostPort = hostPort
+	container.AccessURL = ""
+	if hostPort != 0 {
+		container.AccessURL = fmt.Sprintf("http://localhost:%d", hostPort)
+	}
+	if container.ContainerName == lab.ContainerName {
+		return s.deliverFlagFile(ctx, lab, container.ContainerName)
+	}
+	return nil
+}
+
+// Основной сервис — первый сервис с терминалом, а если терминалов нет, то первый сервис
+func primaryService(services []model.TemplateService) string {
+	for _, svc := range services {
+		if svc.TerminalType != "" {
+			return svc.Name
+		}
+	}
+	return services[0].Name
+}
+
+// Переносит в лабораторию параметры основного контейнера (см. primaryService)
+func (s *LabService) setPrimaryContainer(lab *model.Lab) {
+	if len(lab.Containers) == 0 {
+		return
+	}
+	primary := lab.Containers[0]
+	for _, container := range lab.Containers {
+		if container.TerminalType != "" {
+			primary = container
+			break
+		}
+	}
+	lab.ContainerID = primary.ContainerID
+	lab.ContainerName = primary.ContainerName
+	lab.Image = primary.Image
+	lab.CommitImage = primary.Image
+	lab.TerminalType = primary.TerminalType
+	lab.HostPort = primary.HostPort
+	lab.AccessURL = primary.AccessURL
+}
+
+// Запускает все сервисы лаборатории, пересоздавая удалённые контейнеры и сеть
+func (s *LabService) startLabGroup(ctx context.Context, lab *model.Lab) (*StartResult, error) {
+	if _, err := s.docker(ctx, "network", "inspect", lab.NetworkName); err != nil {
+		s.Logger.InfoContext(ctx, "Lab network is missing, recreating", "network", lab.NetworkName)
+		if _, err := s.docker(ctx, "network", "create", lab.NetworkName); err != nil {
+			return nil, fmt.Errorf("failed to create network %s: %w", lab.NetworkName, err)
+		}
+	}
+
+	result := &StartResult{Mode: StartModeStarted}
+	for i := range lab.Containers {
+		container := &lab.Containers[i]
+		serviceResult, err := s.startLabContainer(ctx, lab, container)
+		if err != nil {
+			return nil, err
+		}
+		if serviceResult.Mode != StartModeStarted {
+			result.Mode = serviceResult.Mode
+		}
+		result.Services = append(result.Services, *serviceResult)
+	}
+
+	s.setPrimaryContainer(lab)
+	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
+		return nil, fmt.Errorf("failed to update lab %d: %w", lab.ID, err)
+	}
+	result.ContainerID = lab.ContainerID
+	result.AccessURL = lab.AccessURL
+	return result, nil
+}
+
+// Запускает контейнер сервиса или пересоздаёт его из последнего снимка либо образа сервиса
+func (s *LabService) startLabContainer(ctx context.Context, lab *model.Lab, container *model.LabContainer) (*ServiceStartResult, error) {
+	exists, err := s.containerExists(ctx, container.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if _, err := s.docker(ctx, "start", container.ContainerName); err != nil {
+			s.Logger.ErrorContext(ctx, "Error while starting service container", "error", err, "service", container.ServiceName)
+			return nil, fmt.Errorf("error while starting container %s: %w", container.ContainerName, err)
+		}
+		return &ServiceStartResult{
+			Service:     container.ServiceName,
+			ContainerID: container.ContainerID,
+			AccessURL:   container.AccessURL,
+			Mode:        StartModeStarted,
+		}, nil
+	}
+
+	mode := StartModeRecreatedSnapshot
+	image, err := s.latestSnapshot(ctx, container.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+	if image == "" {
+		mode = StartModeRecreatedImage
+		image = container.Image
+	}
+	s.Logger.InfoContext(ctx, "Service container is missing, recreating",
+		"lab_id", lab.ID, "service", container.ServiceName, "image", image, "mode", mode)
+
+	if err := s.runLabContainer(ctx, lab, container, image); err != nil {
+		return nil, err
+	}
+	if err := s.LabRepository.UpdateLabContainer(ctx, container); err != nil {
+		return nil, fmt.Errorf("failed to update container of service %s: %w", container.ServiceName, err)
+	}
+	return &ServiceStartResult{
+		Service:     container.ServiceName,
+		ContainerID: container.ContainerID,
+		AccessURL:   container.AccessURL,
+		Mode:        mode,
+		Image:       image,
+	}, nil
+}
+
+// Останавливает сервисы лаборатории в порядке, обратном запуску
+func (s *LabService) stopLabGroup(ctx context.Context, lab *model.Lab) error {
+	for i := len(lab.Containers) - 1; i >= 0; i-- {
+		container := lab.Containers[i]
+		if _, err := s.docker(ctx, "stop", container.ContainerName); err != nil {
+			s.Logger.ErrorContext(ctx, "Error while stopping service container", "error", err, "service", container.ServiceName)
+			return fmt.Errorf("error while stopping container %s: %w", container.ContainerName, err)
+		}
+	}
+	return nil
+}
+
+// Удаляет все контейнеры и сеть лаборатории. Ошибки логируются, а удаление продолжается,
+// чтобы после неудачного создания не оставалось висящих контейнеров.
+func (s *LabService) removeLabGroup(ctx context.Context, lab *model.Lab) error {
+	var lastError error
+	for i := len(lab.Containers) - 1; i >= 0; i-- {
+		container := lab.Containers[i]
+		if _, err := s.docker(ctx, "rm", "-f", container.ContainerName); err != nil {
+			s.Logger.WarnContext(ctx, "Failed to remove service container", "error", err, "service", container.ServiceName)
+			lastError = err
+			continue
+		}
+		s.Ports.Release(container.HostPort)
+	}
+	if lab.NetworkName != "" {
+		if _, err := s.docker(ctx, "network", "rm", lab.NetworkName); err != nil {
+			s.Logger.WarnContext(ctx, "Failed to remove lab network", "error", err, "network", lab.NetworkName)
+			lastError = err
+		}
+	}
+	if lastError != nil {
+		return fmt.Errorf("some lab containers were not removed, last error: %w", lastError)
+	}
+	return nil
+}
+
+// CommitLabGroup делает снимки всех сервисов лаборатории и возвращает имена образов по сервисам
+func (s *LabService) CommitLabGroup(ctx context.Context, lab *model.Lab) (map[string]string, error) {
+	images := make(map[string]string, len(lab.Containers))
+	for _, container := range lab.Containers {
+		imageName, err := s.CommitLab(ctx, container.ContainerName)
+		if err != nil {
+			return images, fmt.Errorf("failed to commit service %s: %w", container.ServiceName, err)
+		}
+		images[container.ServiceName] = imageName
+	}
+	return images, nil
+}
+
+// DeleteLabGroupCommits удаляет снимки всех сервисов лаборатории
+func (s *LabService) DeleteLabGroupCommits(ctx context.Context, lab *model.Lab) error {
+	var lastError error
+	for _, container := range lab.Containers {
+		if err := s.DeleteContainerCommits(ctx, container.ContainerName); err != nil {
+			lastError = err
+		}
+	}
+	return lastError
+}
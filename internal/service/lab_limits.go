@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lab/internal/model"
+	"time"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("lab quota exceeded")
+	ErrOwnerRequired = errors.New("lab owner is required")
+)
+
+// LabLimits — общие для всех лабораторий ограничения из конфигурации
+type LabLimits struct {
+	DefaultCPU     string // Для заданий без cpu_limit
+	DefaultMemory  string // Для заданий без memory_limit
+	DefaultPids    int    // Для заданий без pids_limit
+	MaxLabsPerUser int    // Неистёкших лабораторий на пользователя, 0 — без ограничения
+	DefaultTTL     time.Duration
+	MaxTTL         time.Duration // 0 — без ограничения
+	TrashRetention time.Duration // Сколько лаборатория хранится в корзине до окончательного удаления
+}
+
+// Дополняет задание ограничениями по умолчанию и возвращает срок жизни лаборатории
+func (l LabLimits) apply(task *model.TaskDefinition) time.Duration {
+	if task.CPULimit == "" {
+		task.CPULimit = l.DefaultCPU
+	}
+	if task.MemoryLimit == "" {
+		task.MemoryLimit = l.DefaultMemory
+	}
+	if task.PidsLimit == 0 {
+		task.PidsLimit = l.DefaultPids
+	}
+	ttl := l.DefaultTTL
+	if task.TTLMinutes > 0 {
+		ttl = time.Duration(task.TTLMinutes) * time.Minute
+	}
+	if l.MaxTTL > 0 && (ttl == 0 || ttl > l.MaxTTL) {
+		ttl = l.MaxTTL
+	}
+	return ttl
+}
+
+// Дополняет сервис шаблона ограничениями по умолчанию. Лимит процессов у сервисов общий,
+// он берётся из задания лаборатории.
+func (l LabLimits) applyService(svc *model.TemplateService) {
+	if svc.CPULimit == "" {
+		svc.CPULimit = l.DefaultCPU
+	}
+	if svc.MemoryLimit == "" {
+		svc.MemoryLimit = l.DefaultMemory
+	}
+}
+
+// Проверяет, что пользователь не превысил лимит лабораторий. Анонимные лаборатории не создаются:
+// у них нет владельца, на которого считается лимит.
+func (s *LabService) checkQuota(ctx context.Context, ownerID uint) error {
+	if ownerID == 0 {
+		return ErrOwnerRequired
+	}
+	if s.Limits.MaxLabsPerUser == 0 {
+		return nil
+	}
+	count, err := s.LabRepository.CountOwnerLabs(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to count user labs: %w", err)
+	}
+	if count >= int64(s.Limits.MaxLabsPerUser) {
+		return fmt.Errorf("%w: user %d already has %d labs", ErrQuotaExceeded, ownerID, count)
+	}
+	return nil
+}
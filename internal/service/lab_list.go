@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lab/internal/model"
+	"lab/internal/tracing"
+	"regexp"
+	"time"
+)
+
+// Размер страницы списка лабораторий
+const (
+	defaultLabPageSize = 50
+	maxLabPageSize     = 200
+)
+
+// Ограничения меток лаборатории
+const (
+	maxLabels           = 32
+	maxLabelValueLength = 256
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLabels = errors.New("invalid labels")
+
+	labelKeyPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]{0,62})$`)
+)
+
+// LabPage — страница списка лабораторий. NextCursor пуст на последней странице.
+type LabPage struct {
+	Labs       []*model.Lab `json:"labs"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// Содержимое курсора; сортировка запоминается, чтобы курсор нельзя было применить к другому порядку
+type labCursor struct {
+	SortBy string    `json:"s"`
+	Desc   bool      `json:"d"`
+	Time   time.Time `json:"t"`
+	ID     uint      `json:"i"`
+}
+
+// ListLabs возвращает страницу лабораторий. cursor — значение next_cursor предыдущей страницы.
+func (s *LabService) ListLabs(ctx context.Context, filter model.LabFilter, cursor string) (_ *LabPage, err error) {
+	ctx, span := tracing.Start(ctx, "LabService.ListLabs")
+	defer func() { tracing.End(span, err) }()
+
+	if filter.SortBy != model.LabSortUpdatedAt {
+		filter.SortBy = model.LabSortCreatedAt
+	}
+	// Ключи фильтра проверяются так же, как ключи меток: из них строятся пути JSON в SQLite
+	if err := validateLabels(filter.Labels); err != nil {
+		return nil, err
+	}
+	for _, key := range filter.LabelKeys {
+		if !labelKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: invalid label key %q", ErrInvalidLabels, key)
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLabPageSize
+	}
+	if filter.Limit > maxLabPageSize {
+		filter.Limit = maxLabPageSize
+	}
+	if cursor != "" {
+		after, err := decodeLabCursor(cursor, filter.SortBy, filter.Desc)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	labs, err := s.LabRepository.ListLabs(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labs: %w", err)
+	}
+	page := &LabPage{Labs: labs}
+	if len(labs) > limit {
+		page.Labs = labs[:limit]
+		last := page.Labs[limit-1]
+		position := last.CreatedAt
+		if filter.SortBy == model.LabSortUpdatedAt {
+			position = last.UpdatedAt
+		}
+		page.NextCursor = encodeLabCursor(labCursor{SortBy: filter.SortBy, Desc: filter.Desc, Time: position, ID: last.ID})
+	}
+	return page, nil
+}
+
+func encodeLabCursor(cursor labCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeLabCursor(value, sortBy string, desc bool) (*model.LabCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor labCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != sortBy || cursor.Desc != desc {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+	return &model.LabCursor{Time: cursor.Time, ID: cursor.ID}, nil
+}
+
+// Проверяет ключи и значения меток лаборатории
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("%w: at most %d labels allowed", ErrInvalidLabels, maxLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: key %q must be 1-63 lowercase letters, digits, '.', '_', '/' or '-'", ErrInvalidLabels, key)
+		}
+		if len(value) > maxLabelValueLength {
+			return fmt.Errorf("%w: value of %q is longer than %d bytes", ErrInvalidLabels, key, maxLabelValueLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"lab/internal/model"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength = 200
+	maxNotesLength = 10000
+)
+
+var (
+	ErrVersionConflict = errors.New("lab was modified concurrently")
+	ErrInvalidPatch    = errors.New("invalid lab update")
+)
+
+// PatchLab меняет название, метки, заметки и срок жизни лаборатории. version — версия,
+// на которой клиент основывал изменения (из If-Match), 0 — без проверки. Запись обновляется
+// только при неизменной версии, поэтому одновременные правки не затирают друг друга.
+func (s *LabService) PatchLab(ctx context.Context, labID uint, version uint, patch model.LabPatch) (_ *model.Lab, err error) {
+	if err := s.validatePatch(patch); err != nil {
+		return nil, err
+	}
+	// Блокировка не даёт операциям жизненного цикла перезаписать изменения сохранением всей записи
+	ctx, done, err := s.startLabOperation(ctx, labID, "update", "LabService.PatchLab", attribute.Int("lab.id", int(labID)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { done(err) }()
+
+	lab, err := s.GetLab(ctx, labID)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Status == model.LabStatusTrashed {
+		return nil, fmt.Errorf("%w: %d", ErrLabTrashed, lab.ID)
+	}
+	if version != 0 && lab.Version != version {
+		return nil, fmt.Errorf("%w: lab %d is at version %d, not %d", ErrVersionConflict, lab.ID, lab.Version, version)
+	}
+
+	var columns []string
+	if patch.Title != nil {
+		lab.Title = *patch.Title
+		columns = append(columns, "title")
+	}
+	if patch.Labels != nil {
+		lab.Labels = *patch.Labels
+		columns = append(columns, "labels")
+	}
+	if patch.Notes != nil {
+		lab.Notes = *patch.Notes
+		columns = append(columns, "notes")
+	}
+	if patch.TTLMinutes != nil {
+		lab.ExpiresAt = nil
+		if *patch.TTLMinutes > 0 {
+			expiresAt := time.Now().Add(time.Duration(*patch.TTLMinutes) * time.Minute)
+			lab.ExpiresAt = &expiresAt
+		}
+		columns = append(columns, "expires_at")
+		// Продлённую истёкшую лабораторию снова можно запустить
+		if lab.Status == model.LabStatusExpired {
+			lab.Status = model.LabStatusStopped
+			columns = append(columns, "status")
+		}
+	}
+
+	current := lab.Version
+	updated, err := s.LabRepository.UpdateLabFields(ctx, lab, current, columns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lab %d: %w", lab.ID, err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: lab %d changed after version %d was read", ErrVersionConflict, lab.ID, current)
+	}
+	s.publish(ctx, model.EventLabUpdated, lab, map[string]any{"fields": columns, "version": lab.Version})
+	s.Logger.InfoContext(ctx, "Lab updated", "lab_id", lab.ID, "fields", columns, "version", lab.Version)
+	return lab, nil
+}
+
+func (s *LabService) validatePatch(patch model.LabPatch) error {
+	if patch.Title == nil && patch.Labels == nil && patch.Notes == nil && patch.TTLMinutes == nil {
+		return fmt.Errorf("%w: nothing to update", ErrInvalidPatch)
+	}
+	if patch.Title != nil && utf8.RuneCountInString(*patch.Title) > maxTitleLength {
+		return fmt.Errorf("%w: title is longer than %d characters", ErrInvalidPatch, maxTitleLength)
+	}
+	if patch.Notes != nil && utf8.RuneCountInString(*patch.Notes) > maxNotesLength {
+		return fmt.Errorf("%w: notes are longer than %d characters", ErrInvalidPatch, maxNotesLength)
+	}
+	if patch.Labels != nil {
+		if err := validateLabels(*patch.Labels); err != nil {
+			return err
+		}
+	}
+	if patch.TTLMinutes != nil {
+		ttl := time.Duration(*patch.TTLMinutes) * time.Minute
+		switch {
+		case ttl < 0:
+			return fmt.Errorf("%w: ttl_minutes must not be negative", ErrInvalidPatch)
+		case s.Limits.MaxTTL > 0 && ttl == 0:
+			return fmt.Errorf("%w: lab must expire within %s", ErrInvalidPatch, s.Limits.MaxTTL)
+		case s.Limits.MaxTTL > 0 && ttl > s.Limits.MaxTTL:
+			return fmt.Errorf("%w: ttl_minutes exceeds the maximum of %s", ErrInvalidPatch, s.Limits.MaxTTL)
+		}
+	}
+	return nil
+}
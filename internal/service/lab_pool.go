@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"lab/internal/model"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+var ErrNotPoolable = errors.New("task can not be served from warm pool")
+
+// Сколько лабораторий пула CreateLab пробует выдать, прежде чем создать лабораторию обычным путём
+const poolClaimCandidates = 5
+
+// Окружение контейнера задаётся при его создании, а владелец лаборатории пула становится известен
+// только при выдаче. Поэтому пул доступен заданиям, окружение которых от владельца не зависит:
+// без {{user.id}} и {{lab.flag}} в шаблонах и с флагом в файле, который перезаписывается при выдаче.
+func poolable(task *model.TaskDefinition, template *model.LabTemplate) error {
+	if task.Flag != nil && (task.Flag.Env != "" || task.Flag.File == "") {
+		return fmt.Errorf("%w: flag is passed in environment, use flag.file", ErrNotPoolable)
+	}
+	envs := []map[string]string{task.Env}
+	if template != nil {
+		for _, svc := range template.Services {
+			envs = append(envs, svc.Env)
+		}
+	}
+	for _, env := range envs {
+		for key, value := range env {
+			for _, match := range envTemplatePattern.FindAllStringSubmatch(value, -1) {
+				if match[1] == "user.id" || match[1] == "lab.flag" {
+					return fmt.Errorf("%w: env %s depends on lab owner", ErrNotPoolable, key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Совпадает ли лаборатория пула с текущим определением задания: задание или шаблон
+// могли измениться после того, как лаборатория была создана
+func pooledLabMatches(lab *model.Lab, task *model.TaskDefinition, template *model.LabTemplate) bool {
+	if !maps.Equal(lab.Env, task.Env) || !reflect.DeepEqual(lab.Flag, task.Flag) || lab.RecordTerminal != task.RecordTerminal {
+		return false
+	}
+	if template != nil {
+		return lab.TemplateID == template.ID && !template.UpdatedAt.After(lab.CreatedAt)
+	}
+	return lab.TemplateID == 0 && lab.Image == task.VMImagePath && lab.TerminalType == task.TerminalType &&
+		slices.Equal(lab.Ports, task.Ports) && lab.CPULimit == task.CPULimit &&
+		lab.MemoryLimit == task.MemoryLimit && lab.PidsLimit == task.PidsLimit
+}
+
+// Загружает задание и проверяет, что его лаборатории можно создавать заранее
+func (s *LabService) poolTask(ctx context.Context, taskID uint) (*model.TaskDefinition, *model.LabTemplate, error) {
+	task, err := s.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.Limits.apply(task)
+	if err := validateTask(task); err != nil {
+		return nil, nil, err
+	}
+	template, err := s.taskTemplate(ctx, task)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := poolable(task, template); err != nil {
+		return nil, nil, err
+	}
+	return task, template, nil
+}
+
+// CheckPoolable сообщает, можно ли держать для задания тёплый пул
+func (s *LabService) CheckPoolable(ctx context.Context, taskID uint) error {
+	_, _, err := s.poolTask(ctx, taskID)
+	return err
+}
+
+// FillPool создаёт одну лабораторию тёплого пула задания: контейнеры запущены, секреты
+// сгенерированы, но владельца нет. События о такой лаборатории публикуются только при выдаче.
+func (s *LabService) FillPool(ctx context.Context, taskID uint) (err error) {
+	ctx, done, err := s.startOperation(ctx, "pool_fill", "LabService.FillPool", attribute.Int("task.id", int(taskID)))
+	if err != nil {
+		return err
+	}
+	defer func() { done(err) }()
+
+	task, template, err := s.poolTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	lab := newLab(task, 0, task.Title, nil)
+	lab.Status = model.LabStatusPooled
+	if err := s.provisionLab(ctx, lab, task, template); err != nil {
+		return err
+	}
+	s.Logger.InfoContext(ctx, "Warm pool lab created", "lab_id", lab.ID, "task_id", taskID)
+	return nil
+}
+
+// ShrinkPool удаляет до count лабораторий пула задания, начиная с самых старых, и возвращает,
+// сколько удалено. Лаборатории, которые сейчас выдаются пользователю, пропускаются.
+func (s *LabService) ShrinkPool(ctx context.Context, taskID uint, count int) (removed int, err error) {
+	ctx, done, err := s.startOperation(ctx, "pool_shrink", "LabService.ShrinkPool", attribute.Int("task.id", int(taskID)))
+	if err != nil {
+		return 0, err
+	}
+	defer func() { done(err) }()
+
+	labs, err := s.LabRepository.ListLabs(ctx, model.LabFilter{TaskID: taskID, Status: model.LabStatusPooled, Limit: count})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list warm pool labs: %w", err)
+	}
+	for _, lab := range labs {
+		if s.removePooledLabLocked(ctx, lab.ID) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Захватывает блокировку лаборатории пула и удаляет её, если она всё ещё не выдана
+func (s *LabService) removePooledLabLocked(ctx context.Context, labID uint) bool {
+	_, unlock, err := s.Locks.Lock(ctx, labID, "pool_shrink", 0)
+	if err != nil {
+		s.Logger.InfoContext(ctx, "Skipping busy warm pool lab", "lab_id", labID, "error", err)
+		return false
+	}
+	defer unlock()
+
+	lab, err := s.LabRepository.GetLab(ctx, int(labID))
+	if err != nil || lab.Status != model.LabStatusPooled {
+		return false
+	}
+	s.removePooledLab(ctx, lab)
+	return true
+}
+
+// Удаляет контейнеры, секреты и запись лаборатории пула. Вызывающий держит блокировку лаборатории.
+func (s *LabService) removePooledLab(ctx context.Context, lab *model.Lab) {
+	if err := s.removeContainers(ctx, lab); err != nil {
+		s.Logger.WarnContext(ctx, "Failed to remove warm pool lab containers", "error", err, "lab_id", lab.ID)
+	}
+	s.discardLab(ctx, lab)
+	s.Logger.InfoContext(ctx, "Warm pool lab removed", "lab_id", lab.ID, "task_id", lab.TaskID)
+}
+
+// Выдаёт пользователю готовую лабораторию из тёплого пула задания. nil — подходящей лаборатории
+// нет, и её нужно создать обычным путём; ошибки пула поэтому только логируются.
+func (s *LabService) claimPooledLab(ctx context.Context, task *model.TaskDefinition, template *model.LabTemplate, ownerID uint, title string, labels map[string]string, expiresAt *time.Time) *model.Lab {
+	if poolable(task, template) != nil {
+		return nil
+	}
+	candidates, err := s.LabRepository.ListLabs(ctx, model.LabFilter{TaskID: task.ID, Status: model.LabStatusPooled, Limit: poolClaimCandidates})
+	if err != nil {
+		s.Logger.WarnContext(ctx, "Failed to list warm pool labs", "error", err, "task_id", task.ID)
+		return nil
+	}
+	for _, candidate := range candidates {
+		lab, err := s.claimLab(ctx, candidate.ID, task, template, ownerID, title, labels, expiresAt)
+		if err != nil {
+			s.Logger.WarnContext(ctx, "Failed to claim warm pool lab", "error", err, "lab_id", candidate.ID)
+			continue
+		}
+		if lab != nil {
+			s.Logger.InfoContext(ctx, "Lab claimed from warm pool", "lab_id", lab.ID, "owner_id", ownerID)
+			return lab
+		}
+	}
+	return nil
+}
+
+// Назначает лаборатории пула владельца. nil без ошибки — лабораторию уже выдали другому
+// пользователю, она занята или устарела.
+func (s *LabService) claimLab(ctx context.Context, labID uint, task *model.TaskDefinition, template *model.LabTemplate, ownerID uint, title string, labels map[string]string, expiresAt *time.Time) (*model.Lab, error) {
+	_, unlock, err := s.Locks.Lock(ctx, labID, "claim", 0)
+	if err != nil {
+		return nil, nil
+	}
+	defer unlock()
+
+	lab, err := s.LabRepository.GetLab(ctx, int(labID))
+	if err != nil {
+		return nil, err
+	}
+	if lab.Status != model.LabStatusPooled {
+		return nil, nil
+	}
+	if !pooledLabMatches(lab, task, template) {
+		s.Logger.InfoContext(ctx, "Warm pool lab is outdated", "lab_id", lab.ID, "task_id", task.ID)
+		s.removePooledLab(ctx, lab)
+		return nil, nil
+	}
+
+	version := lab.Version
+	pooled := *lab
+	lab.OwnerID = ownerID
+	lab.Title = title
+	lab.Labels = labels
+	lab.ExpiresAt = expiresAt
+	lab.Status = model.LabStatusRunning
+	lab.CreatedAt = time.Now()
+	// Проверка версии защищает от выдачи одной лаборатории дважды, если блокировки локальные, а реплик несколько
+	claimed, err := s.LabRepository.UpdateLabFields(ctx, lab, version, "owner_id", "title", "labels", "expires_at", "status", "created_at")
+	if err != nil || !claimed {
+		return nil, err
+	}
+	// Флаг вычисляется с учётом владельца: записанный при создании пула файл заменяется
+	s.SecretService.UnregisterFlag(&pooled)
+	if err := s.deliverFlagFile(ctx, lab, lab.ContainerName); err != nil {
+		s.removePooledLab(ctx, lab)
+		return nil, err
+	}
+	return lab, nil
+}
@@ -3,210 +3,724 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"io"
+	"lab/internal/events"
 	"lab/internal/interfaces"
+	"lab/internal/metrics"
 	"lab/internal/model"
-	"lab/utils"
+	"lab/internal/tracing"
 	"log/slog"
 	"net/http"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	ErrTaskNotFound = errors.New("task not found")
+	ErrInvalidTask  = errors.New("invalid task definition")
+	ErrShuttingDown = errors.New("service is shutting down")
+
+	ErrContainerNotRunning = errors.New("container is not running")
+	ErrLabForbidden        = errors.New("lab is available only to its owner")
+)
+
+// Начинает операцию над лабораторией: спан трассировки, учёт незавершённых операций для Drain,
+// а по завершении — учёт в метриках. Во время остановки сервиса новые операции отклоняются.
+func (s *LabService) startOperation(ctx context.Context, operation, spanName string, attrs ...attribute.KeyValue) (context.Context, func(error), error) {
+	s.opsMu.Lock()
+	if s.draining {
+		s.opsMu.Unlock()
+		return ctx, nil, ErrShuttingDown
+	}
+	s.ops.Add(1)
+	s.opsMu.Unlock()
+
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, spanName, attrs...)
+	return ctx, func(err error) {
+		metrics.ObserveOperation(operation, start, err)
+		tracing.End(span, err)
+		s.ops.Done()
+	}, nil
+}
+
+// Начинает операцию над существующей лабораторией и захватывает её блокировку, чтобы
+// операции над одной лабораторией (запуск и удаление, снимок и остановка) не перемешивались.
+// Если лаборатория занята дольше LockWait, возвращает ошибку locks.ErrInProgress.
+func (s *LabService) startLabOperation(ctx context.Context, labID uint, operation, spanName string, attrs ...attribute.KeyValue) (context.Context, func(error), error) {
+	ctx, done, err := s.startOperation(ctx, operation, spanName, attrs...)
+	if err != nil {
+		return ctx, nil, err
+	}
+	op, unlock, err := s.Locks.Lock(ctx, labID, operation, s.LockWait)
+	if err != nil {
+		done(err)
+		return ctx, nil, err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("lab.operation_id", op.ID))
+	s.Logger.DebugContext(ctx, "Lab lock acquired", "lab_id", labID, "operation", operation, "operation_id", op.ID)
+	return ctx, func(err error) {
+		unlock()
+		done(err)
+	}, nil
+}
+
+// Drain запрещает новые операции над лабораториями и ждёт завершения начатых,
+// чтобы остановка сервиса не прервала docker run или commit на середине
+func (s *LabService) Drain(ctx context.Context) error {
+	s.opsMu.Lock()
+	s.draining = true
+	s.opsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.ops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("lab operations still running: %w", ctx.Err())
+	}
+}
+
 type LabService struct {
-	LabRepository  interfaces.LabInterface
-	TaskServiceURL string // URL для доступа к сервису заданий
-	Logger         *slog.Logger
+	LabRepository      interfaces.LabInterface
+	TemplateRepository interfaces.LabTemplateInterface
+	SecretService      *SecretService
+	AuditService       *AuditService
+	Events             *events.Bus
+	Ports              *PortAllocator
+	Locks              interfaces.LabLocker
+	LockWait           time.Duration // Сколько операция ждёт освобождения занятой лаборатории
+	Runtime            string        // CLI среды контейнеров, совместимый с docker
+	Limits             LabLimits
+	TaskServiceURL     string // URL для доступа к сервису заданий
+	Logger             *slog.Logger
+
+	opsMu    sync.Mutex
+	ops      sync.WaitGroup // Незавершённые операции над лабораториями
+	draining bool
 }
 
-func NewLabService(labRepository interfaces.LabInterface, taskServiceURL string, logger *slog.Logger) *LabService {
+func NewLabService(labRepository interfaces.LabInterface, templateRepository interfaces.LabTemplateInterface, secretService *SecretService, auditService *AuditService, eventBus *events.Bus, ports *PortAllocator, locker interfaces.LabLocker, lockWait time.Duration, runtime string, limits LabLimits, taskServiceURL string, logger *slog.Logger) *LabService {
 	return &LabService{
-		LabRepository:  labRepository,
-		TaskServiceURL: taskServiceURL,
-		Logger:         logger,
+		LabRepository:      labRepository,
+		TemplateRepository: templateRepository,
+		SecretService:      secretService,
+		AuditService:       auditService,
+		Events:             eventBus,
+		Ports:              ports,
+		Locks:              locker,
+		LockWait:           lockWait,
+		Runtime:            runtime,
+		Limits:             limits,
+		TaskServiceURL:     taskServiceURL,
+		Logger:             logger,
 	}
 }
 
-func (s *LabService) CreateLab(ctx context.Context, taskID uint, vmImagePath string) (string, string, uint, error) {
-	containerName := fmt.Sprintf("lab_%d_%s", taskID, time.Now().Format("20060102_150405_999"))
-	freePort, err := utils.GetFreePort()
+func (s *LabService) CreateLab(ctx context.Context, taskID uint, ownerID uint, title string, labels map[string]string, override *model.TaskDefinition) (containerID string, accessURL string, labID uint, err error) {
+	ctx, done, err := s.startOperation(ctx, "create", "LabService.CreateLab", attribute.Int("task.id", int(taskID)))
 	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error getting free port", "error", err)
-		return "", "", 0, fmt.Errorf("failed to get free port: %w", err)
+		return "", "", 0, err
 	}
-	cmd := exec.CommandContext(
-		ctx,
-		"docker", "run", "-dit",
-		"--name", containerName,
-		"-p", fmt.Sprintf("%d:7681", freePort),
-		vmImagePath,
-	)
+	defer func() { done(err) }()
 
-	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
-	output, err := cmd.CombinedOutput()
+	if err := validateLabels(labels); err != nil {
+		return "", "", 0, err
+	}
+	task, err := s.GetTask(ctx, taskID)
 	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error while creating container", "error", err, "output", string(output))
-		return "", "", 0, fmt.Errorf("error while creating container %s: %w (output: %s)", containerName, err, string(output))
+		return "", "", 0, err
+	}
+	task.ApplyOverride(override)
+	ttl := s.Limits.apply(task)
+	if err := validateTask(task); err != nil {
+		s.Logger.WarnContext(ctx, "Invalid task definition", "task_id", taskID, "error", err)
+		return "", "", 0, err
+	}
+	if err := s.checkQuota(ctx, ownerID); err != nil {
+		return "", "", 0, err
+	}
+	template, err := s.taskTemplate(ctx, task)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	if title == "" {
+		title = task.Title
+	}
+	var expiresAt *time.Time
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		expiresAt = &expires
+	}
+
+	// Контейнеры лаборатории из тёплого пула уже запущены, поэтому переопределить параметры задания нельзя
+	if override == nil {
+		if lab := s.claimPooledLab(ctx, task, template, ownerID, title, labels, expiresAt); lab != nil {
+			s.publish(ctx, model.EventLabCreated, lab, map[string]any{"container_id": lab.ContainerID, "access_url": lab.AccessURL, "warm_pool": true})
+			return lab.ContainerID, lab.AccessURL, lab.ID, nil
+		}
 	}
-	containerID := strings.TrimSpace(string(output))
 
-	accessURL := fmt.Sprintf("http://localhost:%d", freePort)
+	lab := newLab(task, ownerID, title, labels)
+	lab.ExpiresAt = expiresAt
+	if err := s.provisionLab(ctx, lab, task, template); err != nil {
+		if lab.ID != 0 {
+			s.publishFailure(ctx, lab, "create", err)
+		}
+		return "", "", 0, err
+	}
+	s.Logger.DebugContext(ctx, "Lab created", "id", lab.ID)
+	s.publish(ctx, model.EventLabCreated, lab, map[string]any{"container_id": lab.ContainerID, "access_url": lab.AccessURL})
+
+	return lab.ContainerID, lab.AccessURL, lab.ID, nil
+}
+
+// Загружает шаблон задания из нескольких контейнеров; nil — задание с одним образом
+func (s *LabService) taskTemplate(ctx context.Context, task *model.TaskDefinition) (*model.LabTemplate, error) {
+	if task.TemplateID == 0 {
+		return nil, nil
+	}
+	template, err := s.TemplateRepository.GetTemplate(ctx, task.TemplateID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error getting lab template", "error", err, "template_id", task.TemplateID)
+		return nil, fmt.Errorf("%w: template %d: %v", ErrInvalidTask, task.TemplateID, err)
+	}
+	return template, nil
+}
 
-	lab := &model.Lab{
-		TaskID:        taskID,
-		ContainerID:   containerID,
-		ContainerName: containerName,
-		AccessURL:     accessURL,
-		CommitImage:   vmImagePath,
+// Собирает запись новой лаборатории из параметров задания
+func newLab(task *model.TaskDefinition, ownerID uint, title string, labels map[string]string) *model.Lab {
+	return &model.Lab{
+		Title:         title,
+		Labels:        labels,
+		TaskID:        task.ID,
+		OwnerID:       ownerID,
+		ContainerName: fmt.Sprintf("lab_%d_%s", task.ID, time.Now().Format("20060102_150405_999")),
+		CommitImage:   task.VMImagePath,
+		Image:         task.VMImagePath,
+		TerminalType:  task.TerminalType,
+		Ports:         task.Ports,
+		CPULimit:      task.CPULimit,
+		MemoryLimit:   task.MemoryLimit,
+		PidsLimit:     task.PidsLimit,
+		Env:           task.Env,
+		Flag:          task.Flag,
+
+		RecordTerminal: task.RecordTerminal,
+		Status:         model.LabStatusRunning,
 	}
+}
+
+// Сохраняет лабораторию, генерирует её секреты и запускает контейнеры. При ошибке всё созданное
+// удаляется; если ошибка случилась после сохранения записи, lab.ID остаётся заполненным.
+func (s *LabService) provisionLab(ctx context.Context, lab *model.Lab, task *model.TaskDefinition, template *model.LabTemplate) error {
+	// Запись создаётся до контейнера, чтобы ID лаборатории можно было подставить в переменные окружения
 	if err := s.LabRepository.CreateLab(ctx, lab); err != nil {
 		s.Logger.ErrorContext(ctx, "Failed to save lab to database", "error", err)
-		return "", "", 0, fmt.Errorf("failed to save lab to database: %w", err)
+		return fmt.Errorf("failed to save lab to database: %w", err)
+	}
+	if _, err := s.SecretService.GenerateSecrets(ctx, lab.ID, task.Secrets); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to generate lab secrets", "error", err, "lab_id", lab.ID)
+		s.discardLab(ctx, lab)
+		return err
+	}
+
+	var err error
+	if template != nil {
+		err = s.createLabGroup(ctx, lab, template)
+	} else {
+		err = s.runSingleContainer(ctx, lab, lab.Image)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error while creating lab containers", "error", err, "lab_id", lab.ID)
+		if template == nil {
+			s.docker(ctx, "rm", "-f", lab.ContainerName)
+			s.Ports.Release(lab.HostPort)
+		}
+		s.discardLab(ctx, lab)
+		return err
 	}
-	s.Logger.DebugContext(ctx, "Lab created", "id", lab.ID)
 
-	return containerID, accessURL, lab.ID, nil
+	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to save lab to database", "error", err)
+		if template != nil {
+			s.removeLabGroup(ctx, lab)
+		} else {
+			s.docker(ctx, "rm", "-f", lab.ContainerName)
+			s.Ports.Release(lab.HostPort)
+		}
+		s.discardLab(ctx, lab)
+		return fmt.Errorf("failed to save lab to database: %w", err)
+	}
+	return nil
+}
+
+// Удаляет запись и секреты лаборатории, контейнер которой не удалось создать
+func (s *LabService) discardLab(ctx context.Context, lab *model.Lab) {
+	s.SecretService.UnregisterFlag(lab)
+	if err := s.SecretService.DeleteSecrets(ctx, lab.ID); err != nil {
+		s.Logger.WarnContext(ctx, "Failed to delete secrets of discarded lab", "error", err, "lab_id", lab.ID)
+	}
+	if err := s.LabRepository.DeleteLab(ctx, int(lab.ID)); err != nil {
+		s.Logger.WarnContext(ctx, "Failed to delete discarded lab", "error", err, "lab_id", lab.ID)
+	}
+}
+
+// Запускает единственный контейнер лаборатории из образа и заполняет его ID, порт и адрес терминала
+func (s *LabService) runSingleContainer(ctx context.Context, lab *model.Lab, image string) error {
+	env, err := s.labEnv(ctx, lab, lab.Env, true)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	envFile, err := writeEnvFile(env)
+	if err != nil {
+		return err
+	}
+	if envFile != "" {
+		defer os.Remove(envFile)
+	}
+
+	// Прежний контейнер удалён, его порт больше не нужен
+	s.Ports.Release(lab.HostPort)
+	lab.HostPort = 0
+	freePort := 0
+	// Записываемый терминал доступен только через сервис, иначе запись можно обойти через ttyd/wetty
+	if !lab.RecordTerminal {
+		freePort, err = s.Ports.Allocate()
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "Error getting free port", "error", err)
+			return fmt.Errorf("failed to get free port: %w", err)
+		}
+	}
+	cmd := exec.CommandContext(ctx, s.Runtime, containerRunArgs(lab, image, freePort, envFile)...)
+
+	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
+	output, err := runCombined(ctx, cmd)
+	if err != nil {
+		s.Ports.Release(freePort)
+		s.Logger.ErrorContext(ctx, "Error while creating container", "error", err, "output", string(output))
+		return fmt.Errorf("error while creating container %s: %w (output: %s)", lab.ContainerName, err, string(output))
+	}
+	lab.ContainerID = strings.TrimSpace(string(output))
+	lab.HostPort = freePort
+	lab.AccessURL = ""
+	if freePort != 0 {
+		lab.AccessURL = fmt.Sprintf("http://localhost:%d", freePort)
+	}
+	return s.deliverFlagFile(ctx, lab, lab.ContainerName)
+}
+
+// Формирует аргументы docker run для контейнера лаборатории из сохранённых в ней параметров задания.
+// Терминал публикуется на hostPort, если он не 0.
+func containerRunArgs(lab *model.Lab, image string, hostPort int, envFile string) []string {
+	args := []string{"run", "-dit", "--name", lab.ContainerName}
+	if hostPort != 0 {
+		args = append(args, "-p", fmt.Sprintf("%d:%d", hostPort, model.TerminalPort(lab.TerminalType)))
+	}
+	for _, port := range lab.Ports {
+		args = append(args, "-p", strconv.Itoa(port))
+	}
+	if envFile != "" {
+		args = append(args, "--env-file", envFile)
+	}
+	if lab.CPULimit != "" {
+		args = append(args, "--cpus", lab.CPULimit)
+	}
+	if lab.MemoryLimit != "" {
+		args = append(args, "--memory", lab.MemoryLimit)
+	}
+	if lab.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(lab.PidsLimit))
+	}
+	args = append(args, image)
+	if lab.TerminalType == model.TerminalWetty {
+		args = append(args, "--base", "/wetty", "--reverse-proxy")
+	}
+	return args
+}
+
+// Проверяет, что определения задания достаточно для запуска контейнера
+func validateTask(task *model.TaskDefinition) error {
+	if task.VMImagePath == "" && task.TemplateID == 0 {
+		return fmt.Errorf("%w: task %d has no vm_image_path", ErrInvalidTask, task.ID)
+	}
+	switch task.TerminalType {
+	case "":
+		task.TerminalType = model.TerminalTTYD
+	case model.TerminalTTYD, model.TerminalWetty:
+	default:
+		return fmt.Errorf("%w: unknown terminal type %q", ErrInvalidTask, task.TerminalType)
+	}
+	for _, port := range task.Ports {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("%w: invalid port %d", ErrInvalidTask, port)
+		}
+	}
+	if task.PidsLimit < 0 {
+		return fmt.Errorf("%w: invalid pids_limit %d", ErrInvalidTask, task.PidsLimit)
+	}
+	return validateEnv(task.Env, task.Secrets, task.Flag)
 }
 
 func (s *LabService) CreateLabFromCommit(ctx context.Context, lab *model.Lab, imageName string) error {
 	lab.ContainerName = fmt.Sprintf("lab_%d_%s", lab.TaskID, time.Now().Format("20060102_150405_999"))
-	freePort, err := utils.GetFreePort()
+	freePort, err := s.Ports.Allocate()
 	if err != nil {
 		return fmt.Errorf("failed to get free port: %w", err)
 	}
 	cmd := exec.CommandContext(
 		ctx,
-		"docker", "run", "-dit",
+		s.Runtime, "run", "-dit",
 		"--name", lab.ContainerName,
 		"-p", fmt.Sprintf("%d:3000", freePort),
 		imageName,
 		"--base", "/wetty",
 		"--reverse-proxy",
 	)
-	output, err := cmd.CombinedOutput()
+	output, err := runCombined(ctx, cmd)
 	if err != nil {
+		s.Ports.Release(freePort)
 		return fmt.Errorf("failed to run container: %w (output: %s)", err, string(output))
 	}
 
 	lab.ContainerID = strings.TrimSpace(string(output))
+	lab.HostPort = freePort
 	lab.AccessURL = fmt.Sprintf("http://localhost:%d", freePort)
 
 	return s.LabRepository.CreateLab(ctx, lab)
 }
 
-func (s *LabService) getTaskDockerImage(ctx context.Context, taskID uint) (string, error) {
+// GetTask запрашивает определение задания в сервисе заданий
+func (s *LabService) GetTask(ctx context.Context, taskID uint) (_ *model.TaskDefinition, err error) {
+	ctx, span := tracing.Start(ctx, "LabService.GetTask", attribute.Int("task.id", int(taskID)))
+	defer func() { tracing.End(span, err) }()
+
 	url := fmt.Sprintf("%s/tasks/%d", s.TaskServiceURL, taskID)
+	start := time.Now()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Error creating request for task service", "error", err)
-		return "", fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
-	client := &http.Client{Timeout: 10 * time.Second}
+	// Транспорт otelhttp передаёт контекст трассировки в сервис заданий
+	client := &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}
 	resp, err := client.Do(req)
 	if err != nil {
+		metrics.ObserveTaskService("transport_error", start)
 		s.Logger.ErrorContext(ctx, "Error executing request to task service", "error", err)
-		return "", fmt.Errorf("error checking task existence: %w", err)
+		return nil, fmt.Errorf("error checking task existence: %w", err)
 	}
 	defer resp.Body.Close()
 
 	s.Logger.InfoContext(ctx, "Received response from task service", "status_code", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotFound {
+		metrics.ObserveTaskService("not_found", start)
+		return nil, fmt.Errorf("%w: %d", ErrTaskNotFound, taskID)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("task not found")
+		metrics.ObserveTaskService("http_error", start)
+		return nil, fmt.Errorf("task service returned status %d", resp.StatusCode)
 	}
 
 	var response struct {
-		Task struct {
-			VMImagePath string `json:"vm_image_path"`
-		} `json:"task"`
+		Task model.TaskDefinition `json:"task"`
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		metrics.ObserveTaskService("transport_error", start)
 		s.Logger.ErrorContext(ctx, "Error reading task response body", "error", err)
-		return "", fmt.Errorf("error reading response body: %w", err)
+		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
 	s.Logger.DebugContext(ctx, "Task service response body", "body", string(body))
 
 	if err := json.Unmarshal(body, &response); err != nil {
+		metrics.ObserveTaskService("decode_error", start)
 		s.Logger.ErrorContext(ctx, "Error unmarshaling task response", "error", err)
-		return "", fmt.Errorf("error unmarshaling task response: %w", err)
+		return nil, fmt.Errorf("error unmarshaling task response: %w", err)
 	}
+	metrics.ObserveTaskService(metrics.OutcomeOK, start)
+	response.Task.ID = taskID
+
+	return &response.Task, nil
+}
+
+// Способы, которыми StartLab поднял контейнер лаборатории
+const (
+	StartModeStarted           = "started"
+	StartModeRecreatedSnapshot = "recreated_from_snapshot"
+	StartModeRecreatedImage    = "recreated_from_task_image"
+)
+
+// StartResult описывает результат запуска лаборатории
+type StartResult struct {
+	ContainerID string `json:"container_id"`
+	AccessURL   string `json:"access_url"`
+	Mode        string `json:"mode"`
+	Image       string `json:"image,omitempty"`
 
-	return response.Task.VMImagePath, nil
+	Services []ServiceStartResult `json:"services,omitempty"` // Для лабораторий из нескольких контейнеров
 }
 
-func (s *LabService) StartLab(ctx context.Context, lab *model.Lab, vmImagePath string) (string, error) {
-	currLab, err := s.GetLab(ctx, lab.ID)
+// StartLab запускает контейнер лаборатории. Если контейнер был удалён,
+// он пересоздаётся из последнего снимка или из образа задания.
+func (s *LabService) StartLab(ctx context.Context, labID uint) (result *StartResult, err error) {
+	ctx, done, err := s.startLabOperation(ctx, labID, "start", "LabService.StartLab", attribute.Int("lab.id", int(labID)))
 	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error getting lab", "error", err)
-		return "", fmt.Errorf("failed to get lab: %w", err)
+		return nil, err
 	}
-	containerName := currLab.ContainerName
-	cmd := exec.CommandContext(ctx, "docker", "start", containerName)
-	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
-	output, err := cmd.CombinedOutput()
+	defer func() { done(err) }()
+
+	lab, err := s.GetLab(ctx, labID)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Status == model.LabStatusExpired {
+		return nil, fmt.Errorf("%w: %d", ErrLabExpired, lab.ID)
+	}
+	if lab.Status == model.LabStatusTrashed {
+		return nil, fmt.Errorf("%w: %d", ErrLabTrashed, lab.ID)
+	}
+
+	result, err = s.startLab(ctx, lab)
+	if err != nil {
+		s.setStatus(ctx, lab, model.LabStatusFailed)
+		s.publishFailure(ctx, lab, "start", err)
+		return nil, err
+	}
+	s.setStatus(ctx, lab, model.LabStatusRunning)
+	s.publish(ctx, model.EventLabStarted, lab, map[string]any{"mode": result.Mode, "access_url": result.AccessURL})
+	return result, nil
+}
+
+func (s *LabService) startLab(ctx context.Context, lab *model.Lab) (*StartResult, error) {
+	if len(lab.Containers) > 0 {
+		return s.startLabGroup(ctx, lab)
+	}
+
+	exists, err := s.containerExists(ctx, lab.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		cmd := exec.CommandContext(ctx, s.Runtime, "start", lab.ContainerName)
+		s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
+		output, err := runCombined(ctx, cmd)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "Error while starting container", "error", err, "output", string(output))
+			return nil, fmt.Errorf("error while starting container %s: %w (output: %s)", lab.ContainerName, err, string(output))
+		}
+
+		s.Logger.InfoContext(ctx, "Container started successfully", "container_id", lab.ContainerID)
+		return &StartResult{ContainerID: lab.ContainerID, AccessURL: lab.AccessURL, Mode: StartModeStarted}, nil
+	}
+
+	mode := StartModeRecreatedSnapshot
+	image, err := s.latestSnapshot(ctx, lab.ContainerName)
 	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error while starting container", "error", err, "output", string(output))
-		return "", fmt.Errorf("error while starting container %s: %w (output: %s)", containerName, err, string(output))
+		return nil, err
 	}
+	if image == "" {
+		mode = StartModeRecreatedImage
+		image, err = s.recreateImage(ctx, lab)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.Logger.InfoContext(ctx, "Container is missing, recreating",
+		"lab_id", lab.ID, "container", lab.ContainerName, "image", image, "mode", mode)
+
+	if err := s.runSingleContainer(ctx, lab, image); err != nil {
+		return nil, err
+	}
+	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to save recreated container", "error", err, "lab_id", lab.ID)
+		return nil, fmt.Errorf("failed to update lab %d: %w", lab.ID, err)
+	}
+
+	return &StartResult{ContainerID: lab.ContainerID, AccessURL: lab.AccessURL, Mode: mode, Image: image}, nil
+}
+
+// Образ для пересоздания контейнера без снимков: текущий образ задания, а если сервис заданий
+// недоступен или образ в задании не указан — образ, из которого лаборатория была создана
+func (s *LabService) recreateImage(ctx context.Context, lab *model.Lab) (string, error) {
+	task, err := s.GetTask(ctx, lab.TaskID)
+	if err != nil {
+		if lab.Image == "" {
+			s.Logger.ErrorContext(ctx, "Error getting lab task", "error", err, "lab_id", lab.ID, "task_id", lab.TaskID)
+			return "", err
+		}
+		s.Logger.WarnContext(ctx, "Error getting lab task, recreating from the lab image", "error", err, "lab_id", lab.ID, "task_id", lab.TaskID)
+		return lab.Image, nil
+	}
+	if task.VMImagePath == "" {
+		return lab.Image, nil
+	}
+	return task.VMImagePath, nil
+}
 
-	s.Logger.InfoContext(ctx, "Container started successfully", "container_id", strings.TrimSpace(string(output)))
-	return strings.TrimSpace(string(output)), nil
+// Проверяет, существует ли контейнер с указанным именем
+func (s *LabService) containerExists(ctx context.Context, containerName string) (bool, error) {
+	cmd := exec.CommandContext(ctx, s.Runtime, "ps", "-a", "-q",
+		"--filter", fmt.Sprintf("name=^%s$", containerName))
+	output, err := runCombined(ctx, cmd)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error while checking container", "error", err, "output", string(output))
+		return false, fmt.Errorf("error checking container %s: %w (output: %s)", containerName, err, string(output))
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// Возвращает самый свежий снимок контейнера или пустую строку, если снимков нет
+func (s *LabService) latestSnapshot(ctx context.Context, containerName string) (string, error) {
+	cmd := exec.CommandContext(ctx, s.Runtime, "images",
+		"--format", "{{.Repository}}:{{.Tag}}",
+		containerName+"-snapshot-*")
+	output, err := runCombined(ctx, cmd)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error listing snapshots", "error", err, "output", string(output))
+		return "", fmt.Errorf("error listing snapshots of %s: %w (output: %s)", containerName, err, string(output))
+	}
+
+	// В имени снимка есть метка времени, поэтому последний по алфавиту — самый свежий
+	latest := ""
+	for _, image := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if image > latest {
+			latest = image
+		}
+	}
+	return latest, nil
 }
 
-func (s *LabService) StopLab(ctx context.Context, labID int) error {
+func (s *LabService) StopLab(ctx context.Context, labID int) (err error) {
+	ctx, done, err := s.startLabOperation(ctx, uint(labID), "stop", "LabService.StopLab", attribute.Int("lab.id", labID))
+	if err != nil {
+		return err
+	}
+	defer func() { done(err) }()
+
 	lab, err := s.LabRepository.GetLab(ctx, labID)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Error while getting lab", "error", err)
 		return fmt.Errorf("failed to get lab: %w", err)
 	}
-
-	cmd := exec.CommandContext(ctx, "docker", "stop", strings.TrimSpace(lab.ContainerID))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error while stopping container", "error", err, "output", string(output))
-		return fmt.Errorf("error while stopping container %s: %w (output: %s)", lab.ContainerID, err, string(output))
+	// Статус корзины нельзя перезаписывать: иначе лабораторию не восстановить и не удалить окончательно
+	if lab.Status == model.LabStatusTrashed {
+		return fmt.Errorf("%w: %d", ErrLabTrashed, lab.ID)
+	}
+	if err := s.stopContainers(ctx, lab); err != nil {
+		s.publishFailure(ctx, lab, "stop", err)
+		return err
+	}
+	if lab.Status != model.LabStatusExpired {
+		s.setStatus(ctx, lab, model.LabStatusStopped)
 	}
+	s.publish(ctx, model.EventLabStopped, lab, nil)
 
 	s.Logger.InfoContext(ctx, "Lab stopped successfully", "lab_id", lab.ID)
 	return nil
 }
 
-func (s *LabService) UpdateLab(ctx context.Context, lab *model.Lab) error {
-	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
-		s.Logger.ErrorContext(ctx, "Error while updating lab", "error", err, "lab_id", lab.ID)
-		return fmt.Errorf("failed to update lab %d: %w", lab.ID, err)
+// Останавливает все контейнеры лаборатории
+func (s *LabService) stopContainers(ctx context.Context, lab *model.Lab) error {
+	if len(lab.Containers) > 0 {
+		return s.stopLabGroup(ctx, lab)
+	}
+
+	cmd := exec.CommandContext(ctx, s.Runtime, "stop", strings.TrimSpace(lab.ContainerID))
+	output, err := runCombined(ctx, cmd)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error while stopping container", "error", err, "output", string(output))
+		return fmt.Errorf("error while stopping container %s: %w (output: %s)", lab.ContainerID, err, string(output))
 	}
-	s.Logger.InfoContext(ctx, "Lab updated successfully", "lab", lab)
 	return nil
 }
 
-func (s *LabService) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+// ExecuteCommand выполняет команду пользователя в контейнере и записывает её в журнал команд лаборатории
+func (s *LabService) ExecuteCommand(ctx context.Context, labID uint, userID uint, containerID string, command []string) (_ string, err error) {
+	ctx, done, err := s.startOperation(ctx, "exec", "LabService.ExecuteCommand", attribute.Int("lab.id", int(labID)), attribute.String("container.id", containerID))
+	if err != nil {
+		return "", err
+	}
+	defer func() { done(err) }()
+
 	// Объединяем команду в одну строку для исполнения через shell
 	shellCommand := strings.Join(command, " ")
 
+	outputStr, exitCode, err := s.auditedExec(ctx, labID, userID, containerID, shellCommand)
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("exit status %d", exitCode)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Error executing command inside container",
+			"error", err, "container_id", containerID, "output", outputStr)
+		return outputStr, fmt.Errorf("error executing command in container %s: %w (output: %s)", containerID, err, outputStr)
+	}
+
+	s.Logger.InfoContext(ctx, "Command executed successfully in container", "container_id", containerID, "output", outputStr)
+	return outputStr, nil
+}
+
+// ExecInContainer выполняет команду через sh -c внутри контейнера и возвращает вывод и код завершения.
+// Ошибка возвращается, только если команду не удалось запустить.
+func (s *LabService) ExecInContainer(ctx context.Context, containerID string, shellCommand string) (string, int, error) {
 	// Используем sh -c "команда" для запуска через shell
 	args := []string{"exec", containerID, "sh", "-c", shellCommand}
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := exec.CommandContext(ctx, s.Runtime, args...)
 
 	s.Logger.DebugContext(ctx, "Executing command in container", "cmd", cmd.String())
 
-	output, err := cmd.CombinedOutput()
+	output, err := runCombined(ctx, cmd)
 	outputStr := strings.TrimSpace(string(output))
 
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return outputStr, exitErr.ExitCode(), nil
+	}
 	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error executing command inside container",
-			"error", err, "container_id", containerID, "output", outputStr)
-		return outputStr, fmt.Errorf("error executing command in container %s: %w (output: %s)", containerID, err, outputStr)
+		return outputStr, -1, err
 	}
+	return outputStr, 0, nil
+}
 
-	s.Logger.InfoContext(ctx, "Command executed successfully in container", "container_id", containerID, "output", outputStr)
-	return outputStr, nil
+// Выполняет команду через ExecInContainer и записывает её в журнал команд лаборатории
+func (s *LabService) auditedExec(ctx context.Context, labID, userID uint, containerID, shellCommand string) (string, int, error) {
+	startedAt := time.Now()
+	output, exitCode, err := s.ExecInContainer(ctx, containerID, shellCommand)
+	s.AuditService.RecordExec(ctx, labID, userID, containerID, shellCommand, exitCode, time.Since(startedAt), output)
+	return output, exitCode, err
+}
+
+// CheckRunning проверяет, что контейнер существует и запущен. Удалённый контейнер тоже считается остановленным.
+func (s *LabService) CheckRunning(ctx context.Context, container string) error {
+	output, err := s.docker(ctx, "inspect", "--format={{.State.Running}}", container)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrContainerNotRunning, container, err)
+	}
+	if strings.TrimSpace(output) != "true" {
+		return fmt.Errorf("%w: %s", ErrContainerNotRunning, container)
+	}
+	return nil
 }
 
-func (s *LabService) GetAllLabs(ctx context.Context) ([]*model.Lab, error) {
+func (s *LabService) GetAllLabs(ctx context.Context) (_ []*model.Lab, err error) {
+	ctx, span := tracing.Start(ctx, "LabService.GetAllLabs")
+	defer func() { tracing.End(span, err) }()
+
 	labs, err := s.LabRepository.GetAllLabs(ctx)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Error while getting all labs", "error", err)
@@ -216,36 +730,80 @@ func (s *LabService) GetAllLabs(ctx context.Context) ([]*model.Lab, error) {
 	return labs, nil
 }
 
-func (s *LabService) DeleteLab(ctx context.Context, labID int) error {
+// DeleteLab удаляет лабораторию в корзину: контейнеры останавливаются, с них снимается
+// финальный снимок. Окончательно лаборатория удаляется PurgeTrash после срока хранения.
+func (s *LabService) DeleteLab(ctx context.Context, labID int) (err error) {
+	ctx, done, err := s.startLabOperation(ctx, uint(labID), "delete", "LabService.DeleteLab", attribute.Int("lab.id", labID))
+	if err != nil {
+		return err
+	}
+	defer func() { done(err) }()
+
 	lab, err := s.LabRepository.GetLab(ctx, labID)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Error while getting lab", "error", err)
 		return fmt.Errorf("failed to get lab: %w", err)
 	}
-
-	cmdStop := exec.CommandContext(ctx, "docker", "stop", strings.TrimSpace(lab.ContainerID))
-	outputStop, err := cmdStop.CombinedOutput()
-	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error while stopping container", "error", err, "container_id", lab.ContainerID, "output", string(outputStop))
-		return fmt.Errorf("error while stopping container %s: %w (output: %s)", lab.ContainerID, err, string(outputStop))
+	if lab.Status == model.LabStatusTrashed {
+		return fmt.Errorf("%w: %d", ErrLabTrashed, lab.ID)
 	}
 
-	cmdRemove := exec.CommandContext(ctx, "docker", "rm", strings.TrimSpace(lab.ContainerID))
-	outputRemove, err := cmdRemove.CombinedOutput()
+	images, err := s.snapshotForTrash(ctx, lab)
 	if err != nil {
-		s.Logger.ErrorContext(ctx, "Error while removing container", "error", err, "container_id", lab.ContainerID, "output", string(outputRemove))
-		return fmt.Errorf("error while removing container %s: %w (output: %s)", lab.ContainerID, err, string(outputRemove))
+		s.publishFailure(ctx, lab, "delete", err)
+		return err
+	}
+	trashedAt := time.Now()
+	lab.Status = model.LabStatusTrashed
+	lab.TrashedAt = &trashedAt
+	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
+		s.Logger.ErrorContext(ctx, "Error while moving lab to trash", "error", err, "lab_id", labID)
+		s.publishFailure(ctx, lab, "delete", err)
+		return fmt.Errorf("failed to move lab %d to trash: %w", labID, err)
 	}
-	if err := s.LabRepository.DeleteLab(ctx, labID); err != nil {
-		s.Logger.ErrorContext(ctx, "Error while deleting lab", "error", err, "lab_id", labID)
-		return fmt.Errorf("failed to delete lab %d: %w", labID, err)
+	s.publish(ctx, model.EventLabTrashed, lab, map[string]any{
+		"images":      images,
+		"purge_after": trashedAt.Add(s.Limits.TrashRetention),
+	})
+
+	s.Logger.InfoContext(ctx, "Lab moved to trash", "lab_id", labID, "images", images)
+	return nil
+}
+
+// Удаляет все контейнеры и сеть лаборатории, в том числе уже остановленные
+func (s *LabService) removeContainers(ctx context.Context, lab *model.Lab) error {
+	if len(lab.Containers) > 0 {
+		if err := s.removeLabGroup(ctx, lab); err != nil {
+			s.Logger.ErrorContext(ctx, "Error while removing lab containers", "error", err, "lab_id", lab.ID)
+			return err
+		}
+		return nil
 	}
 
-	s.Logger.InfoContext(ctx, "Lab deleted successfully", "lab_id", labID)
+	if _, err := s.docker(ctx, "rm", "-f", lab.ContainerName); err != nil {
+		s.Logger.ErrorContext(ctx, "Error while removing container", "error", err, "container", lab.ContainerName)
+		return err
+	}
+	s.Ports.Release(lab.HostPort)
 	return nil
 }
 
-func (s *LabService) GetLab(ctx context.Context, labID uint) (*model.Lab, error) {
+// GetOwnLab возвращает лабораторию, если запрос выполняет её владелец или администратор (admin)
+func (s *LabService) GetOwnLab(ctx context.Context, labID, userID uint, admin bool) (*model.Lab, error) {
+	lab, err := s.GetLab(ctx, labID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin && (userID == 0 || lab.OwnerID != userID) {
+		return nil, fmt.Errorf("%w: lab %d", ErrLabForbidden, labID)
+	}
+	return lab, nil
+}
+
+func (s *LabService) GetLab(ctx context.Context, labID uint) (_ *model.Lab, err error) {
+	ctx, span := tracing.Start(ctx, "LabService.GetLab", attribute.Int("lab.id", int(labID)))
+	defer func() { tracing.End(span, err) }()
+
 	lab, err := s.LabRepository.GetLab(ctx, int(labID))
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Error while getting lab", "error", err, "lab_id", labID)
@@ -256,12 +814,54 @@ func (s *LabService) GetLab(ctx context.Context, labID uint) (*model.Lab, error)
 	return lab, nil
 }
 
-func (s *LabService) CommitLab(ctx context.Context, containerName string) (string, error) {
-	timestamp := time.Now().Format("20060102-150405")
-	newImageName := fmt.Sprintf("%s-snapshot-%s", containerName, timestamp)
+// SnapshotLab делает снимки контейнеров лаборатории. Возвращает образ основного контейнера
+// и, для лабораторий из нескольких контейнеров, образы по сервисам.
+func (s *LabService) SnapshotLab(ctx context.Context, lab *model.Lab) (_ string, _ map[string]string, err error) {
+	ctx, done, err := s.startLabOperation(ctx, lab.ID, "commit", "LabService.SnapshotLab", attribute.Int("lab.id", int(lab.ID)))
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { done(err) }()
 
-	checkCmd := exec.CommandContext(ctx, "docker", "inspect", "--format={{.State.Running}}", containerName)
-	output, err := checkCmd.CombinedOutput()
+	// Лаборатория могла измениться, пока операция ждала блокировку
+	current, err := s.GetLab(ctx, lab.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	*lab = *current
+	if lab.Status == model.LabStatusTrashed {
+		return "", nil, fmt.Errorf("%w: %d", ErrLabTrashed, lab.ID)
+	}
+
+	if len(lab.Containers) == 0 {
+		imageName, err := s.CommitLab(ctx, lab.ContainerName)
+		if err != nil {
+			s.publishFailure(ctx, lab, "commit", err)
+			return "", nil, err
+		}
+		lab.CommitImage = imageName
+		s.publish(ctx, model.EventLabCommitted, lab, map[string]any{"image": imageName})
+		return imageName, nil, nil
+	}
+
+	images, err := s.CommitLabGroup(ctx, lab)
+	if err != nil {
+		s.publishFailure(ctx, lab, "commit", err)
+		return "", images, err
+	}
+	primaryImage := ""
+	for _, container := range lab.Containers {
+		if container.ContainerName == lab.ContainerName {
+			primaryImage = images[container.ServiceName]
+		}
+	}
+	s.publish(ctx, model.EventLabCommitted, lab, map[string]any{"image": primaryImage, "images": images})
+	return primaryImage, images, nil
+}
+
+func (s *LabService) CommitLab(ctx context.Context, containerName string) (string, error) {
+	checkCmd := exec.CommandContext(ctx, s.Runtime, "inspect", "--format={{.State.Running}}", containerName)
+	output, err := runCombined(ctx, checkCmd)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Container check failed",
 			"container", containerName,
@@ -275,20 +875,28 @@ func (s *LabService) CommitLab(ctx context.Context, containerName string) (strin
 		s.Logger.ErrorContext(ctx, "Container not running", "error", err)
 		return "", err
 	}
+	return s.commitContainer(ctx, containerName, "Autocommit")
+}
+
+// Делает снимок контейнера, в том числе остановленного. Снимок получает имя
+// <контейнер>-snapshot-<время>, по которому его находит latestSnapshot.
+func (s *LabService) commitContainer(ctx context.Context, containerName, message string) (string, error) {
+	timestamp := time.Now().Format("20060102-150405")
+	newImageName := fmt.Sprintf("%s-snapshot-%s", containerName, timestamp)
 	if !strings.Contains(newImageName, ":") {
 		newImageName += ":latest"
 	}
 
-	commitCmd := exec.CommandContext(ctx, "docker", "commit",
+	commitCmd := exec.CommandContext(ctx, s.Runtime, "commit",
 		"-a", "lab-system",
-		"-m", fmt.Sprintf("Autocommit of %s at %s", containerName, timestamp),
+		"-m", fmt.Sprintf("%s of %s at %s", message, containerName, timestamp),
 		containerName,
 		newImageName)
 	s.Logger.DebugContext(ctx, "Executing commit command",
 		"cmd", commitCmd.String(),
 		"new_image", newImageName)
 
-	commitOutput, err := commitCmd.CombinedOutput()
+	commitOutput, err := runCombined(ctx, commitCmd)
 	if err != nil {
 		s.Logger.ErrorContext(ctx, "Commit failed",
 			"error", err,
@@ -305,8 +913,8 @@ func (s *LabService) CommitLab(ctx context.Context, containerName string) (strin
 func (s *LabService) DeleteContainerCommits(ctx context.Context, containerName string) error {
 	imagePattern := containerName + "-snapshot-*"
 
-	cmdListImages := exec.CommandContext(ctx, "docker", "images", "-q", imagePattern)
-	output, err := cmdListImages.CombinedOutput()
+	cmdListImages := exec.CommandContext(ctx, s.Runtime, "images", "-q", imagePattern)
+	output, err := runCombined(ctx, cmdListImages)
 	if err != nil {
 		return fmt.Errorf("error listing images: %w", err)
 	}
@@ -322,8 +930,8 @@ func (s *LabService) DeleteContainerCommits(ctx context.Context, containerName s
 			continue
 		}
 
-		cmdRemoveImage := exec.CommandContext(ctx, "docker", "rmi", "-f", imageID)
-		if _, err := cmdRemoveImage.CombinedOutput(); err != nil {
+		cmdRemoveImage := exec.CommandContext(ctx, s.Runtime, "rmi", "-f", imageID)
+		if _, err := runCombined(ctx, cmdRemoveImage); err != nil {
 			s.Logger.WarnContext(ctx, "Failed to delete image",
 				"image_id", imageID,
 				"error", err)
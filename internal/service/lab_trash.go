@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"lab/internal/model"
+	"time"
+)
+
+var (
+	ErrLabTrashed       = errors.New("lab is in trash")
+	ErrLabNotTrashed    = errors.New("lab is not in trash")
+	ErrRetentionExpired = errors.New("lab retention period has passed")
+)
+
+// Имена всех контейнеров лаборатории
+func containerNames(lab *model.Lab) []string {
+	if len(lab.Containers) == 0 {
+		return []string{lab.ContainerName}
+	}
+	names := make([]string, 0, len(lab.Containers))
+	for _, container := range lab.Containers {
+		names = append(names, container.ContainerName)
+	}
+	return names
+}
+
+// Останавливает контейнеры лаборатории и делает с них финальные снимки.
+// Возвращает образы по именам контейнеров; удалённые вручную контейнеры пропускаются.
+func (s *LabService) snapshotForTrash(ctx context.Context, lab *model.Lab) (map[string]string, error) {
+	names := containerNames(lab)
+	images := make(map[string]string, len(names))
+	for _, name := range names {
+		exists, err := s.containerExists(ctx, name)
+		if err != nil {
+			return images, err
+		}
+		if !exists {
+			s.Logger.WarnContext(ctx, "Container is missing, nothing to snapshot", "lab_id", lab.ID, "container", name)
+			continue
+		}
+		if _, err := s.docker(ctx, "stop", name); err != nil {
+			return images, err
+		}
+		image, err := s.commitContainer(ctx, name, "Final snapshot")
+		if err != nil {
+			return images, err
+		}
+		images[name] = image
+	}
+	return images, nil
+}
+
+// RestoreLab возвращает лабораторию из корзины в остановленном состоянии
+func (s *LabService) RestoreLab(ctx context.Context, labID uint) (_ *model.Lab, err error) {
+	ctx, done, err := s.startLabOperation(ctx, labID, "restore", "LabService.RestoreLab", attribute.Int("lab.id", int(labID)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { done(err) }()
+
+	lab, err := s.GetLab(ctx, labID)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Status != model.LabStatusTrashed || lab.TrashedAt == nil {
+		return nil, fmt.Errorf("%w: %d", ErrLabNotTrashed, lab.ID)
+	}
+	if time.Since(*lab.TrashedAt) > s.Limits.TrashRetention {
+		return nil, fmt.Errorf("%w: lab %d was trashed at %s", ErrRetentionExpired, lab.ID, lab.TrashedAt.Format(time.RFC3339))
+	}
+
+	lab.Status = model.LabStatusStopped
+	lab.TrashedAt = nil
+	if err := s.LabRepository.UpdateLab(ctx, lab); err != nil {
+		return nil, fmt.Errorf("failed to restore lab %d: %w", lab.ID, err)
+	}
+	s.publish(ctx, model.EventLabRestored, lab, nil)
+	s.Logger.InfoContext(ctx, "Lab restored from trash", "lab_id", lab.ID)
+	return lab, nil
+}
+
+// PurgeTrash окончательно удаляет лаборатории, пролежавшие в корзине дольше срока хранения:
+// контейнеры, сеть, снимки, секреты и запись в базе
+func (s *LabService) PurgeTrash(ctx context.Context) error {
+	labs, err := s.LabRepository.GetTrashedLabs(ctx, time.Now().Add(-s.Limits.TrashRetention))
+	if err != nil {
+		return fmt.Errorf("failed to get trashed labs: %w", err)
+	}
+	for _, lab := range labs {
+		if err := s.purgeLab(ctx, lab); err != nil {
+			// Лаборатория останется в корзине, и удаление повторится при следующем проходе
+			s.Logger.ErrorContext(ctx, "Failed to purge lab", "error", err, "lab_id", lab.ID)
+		}
+	}
+	return nil
+}
+
+func (s *LabService) purgeLab(ctx context.Context, lab *model.Lab) (err error) {
+	ctx, done, err := s.startLabOperation(ctx, lab.ID, "purge", "LabService.PurgeLab", attribute.Int("lab.id", int(lab.ID)))
+	if err != nil {
+		return err
+	}
+	defer func() { done(err) }()
+
+	// Пока лаборатория ждала в очереди, её могли восстановить
+	lab, err = s.GetLab(ctx, lab.ID)
+	if err != nil {
+		return err
+	}
+	if lab.Status != model.LabStatusTrashed || lab.TrashedAt == nil || time.Since(*lab.TrashedAt) < s.Limits.TrashRetention {
+		s.Logger.InfoContext(ctx, "Lab is no longer due for purge", "lab_id", lab.ID, "status", lab.Status)
+		return nil
+	}
+
+	if err := s.removeContainers(ctx, lab); err != nil {
+		// rm -f завершается ошибкой и для контейнеров, удалённых вручную: проверяем, что не осталось ни одного
+		for _, name := range containerNames(lab) {
+			exists, checkErr := s.containerExists(ctx, name)
+			if checkErr != nil || exists {
+				return err
+			}
+		}
+		s.Ports.Release(lab.HostPort)
+		for _, container := range lab.Containers {
+			s.Ports.Release(container.HostPort)
+		}
+	}
+	if len(lab.Containers) > 0 {
+		err = s.DeleteLabGroupCommits(ctx, lab)
+	} else {
+		err = s.DeleteContainerCommits(ctx, lab.ContainerName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete lab snapshots: %w", err)
+	}
+	if err := s.SecretService.DeleteSecrets(ctx, lab.ID); err != nil {
+		s.Logger.WarnContext(ctx, "Error while deleting lab secrets", "error", err, "lab_id", lab.ID)
+	}
+	s.SecretService.UnregisterFlag(lab)
+	if err := s.LabRepository.DeleteLab(ctx, int(lab.ID)); err != nil {
+		return fmt.Errorf("failed to delete lab %d: %w", lab.ID, err)
+	}
+	s.publish(ctx, model.EventLabDeleted, lab, nil)
+	s.Logger.InfoContext(ctx, "Lab purged from trash", "lab_id", lab.ID)
+	return nil
+}
+
+// RunPurgeWorker периодически вызывает PurgeTrash, пока не отменён ctx.
+// Как и у RunExpiryWorker, отмена ctx не прерывает начатый проход.
+func (s *LabService) RunPurgeWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.PurgeTrash(context.WithoutCancel(ctx)); err != nil {
+				s.Logger.ErrorContext(ctx, "Trash purge failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var ErrNoFreePorts = errors.New("no free host ports in range")
+
+// PortAllocator выдаёт порты хоста для терминалов лабораторий из диапазона [start, end].
+// Выданные порты считаются занятыми до Release, даже если контейнер остановлен.
+type PortAllocator struct {
+	mu    sync.Mutex
+	start int
+	end   int
+	next  int
+	used  map[int]bool
+}
+
+func NewPortAllocator(start, end int) *PortAllocator {
+	return &PortAllocator{
+		start: start,
+		end:   end,
+		next:  start,
+		used:  make(map[int]bool),
+	}
+}
+
+// Allocate возвращает свободный порт. Порт должен быть не выдан и не занят другим процессом на хосте.
+func (a *PortAllocator) Allocate() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	size := a.end - a.start + 1
+	for i := 0; i < size; i++ {
+		port := a.start + (a.next-a.start+i)%size
+		if a.used[port] || !hostPortFree(port) {
+			continue
+		}
+		a.used[port] = true
+		a.next = port + 1
+		if a.next > a.end {
+			a.next = a.start
+		}
+		return port, nil
+	}
+	return 0, fmt.Errorf("%w %d-%d", ErrNoFreePorts, a.start, a.end)
+}
+
+// Reserve помечает порт занятым, например для лабораторий, созданных до перезапуска
+func (a *PortAllocator) Reserve(port int) {
+	if port < a.start || port > a.end {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.used[port] = true
+}
+
+// Release возвращает порт в диапазон
+func (a *PortAllocator) Release(port int) {
+	if port == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.used, port)
+}
+
+// Usage возвращает число выданных портов и размер диапазона
+func (a *PortAllocator) Usage() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.used), a.end - a.start + 1
+}
+
+// ReservePorts помечает занятыми порты уже созданных лабораторий, чтобы после перезапуска
+// сервиса аллокатор не выдал их повторно
+func (s *LabService) ReservePorts(ctx context.Context) error {
+	labs, err := s.LabRepository.GetAllLabs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load labs: %w", err)
+	}
+	for _, lab := range labs {
+		s.Ports.Reserve(lab.HostPort)
+		for _, container := range lab.Containers {
+			s.Ports.Reserve(container.HostPort)
+		}
+	}
+	return nil
+}
+
+// Проверяет, что порт хоста никто не слушает
+func hostPortFree(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
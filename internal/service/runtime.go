@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"lab/internal/metrics"
+	"lab/internal/tracing"
+	"os/exec"
+	"time"
+)
+
+// Подкоманды docker, у которых в метке метрики указывается и действие: "network create", "image rm"
+var runtimeGroups = map[string]bool{"network": true, "image": true, "container": true, "volume": true}
+
+// runtimeCall — один вызов CLI среды контейнеров, учитываемый в метриках и трассировке
+type runtimeCall struct {
+	command string
+	start   time.Time
+	span    trace.Span
+}
+
+// Начинает учёт вызова; спан становится дочерним для спана из ctx
+func startRuntime(ctx context.Context, cmd *exec.Cmd) *runtimeCall {
+	command := runtimeCommand(cmd.Args)
+	_, span := tracing.Start(ctx, "runtime "+command, attribute.String("runtime.command", command))
+	return &runtimeCall{command: command, start: time.Now(), span: span}
+}
+
+func (c *runtimeCall) end(err error) {
+	metrics.ObserveRuntime(c.command, c.start, err)
+	tracing.End(c.span, err)
+}
+
+// Выполняет команду среды контейнеров и возвращает объединённый вывод.
+// Все вызовы docker проходят здесь или через startRuntime, чтобы попасть в метрики и трассировку.
+func runCombined(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	call := startRuntime(ctx, cmd)
+	output, err := cmd.CombinedOutput()
+	call.end(err)
+	return output, err
+}
+
+func runtimeCommand(args []string) string {
+	if len(args) < 2 {
+		return "unknown"
+	}
+	if runtimeGroups[args[1]] && len(args) > 2 {
+		return args[1] + " " + args[2]
+	}
+	return args[1]
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"lab/internal/interfaces"
+	"lab/internal/logging"
+	"lab/internal/model"
+	"lab/utils"
+	"log/slog"
+	"math/big"
+)
+
+const (
+	SecretFormatHex   = "hex"
+	SecretFormatAlnum = "alnum"
+
+	defaultSecretLength = 32
+	maxSecretLength     = 256
+)
+
+const alnumAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// SecretService генерирует секреты лабораторий, хранит их зашифрованными
+// и регистрирует значения в Redactor, чтобы они не попадали в логи
+type SecretService struct {
+	SecretRepository interfaces.LabSecretInterface
+	EncryptionKey    string
+	FlagSecret       string // Ключ HMAC для флагов CTF
+	Redactor         *logging.Redactor
+	Logger           *slog.Logger
+}
+
+func NewSecretService(secretRepository interfaces.LabSecretInterface, encryptionKey string, flagSecret string, redactor *logging.Redactor, logger *slog.Logger) *SecretService {
+	return &SecretService{
+		SecretRepository: secretRepository,
+		EncryptionKey:    encryptionKey,
+		FlagSecret:       flagSecret,
+		Redactor:         redactor,
+		Logger:           logger,
+	}
+}
+
+// LabFlag возвращает флаг лаборатории или пустую строку, если задание не использует флаги.
+// Флаг не хранится: он каждый раз выводится через HMAC из ID лаборатории, задания и владельца.
+// Сравнение с отправленными флагами не регистрирует их в Redactor, это делает RegisterFlag.
+func (s *SecretService) LabFlag(lab *model.Lab) string {
+	if lab.Flag == nil {
+		return ""
+	}
+	prefix := lab.Flag.Prefix
+	if prefix == "" {
+		prefix = "flag"
+	}
+	mac := hmac.New(sha256.New, []byte(s.FlagSecret))
+	fmt.Fprintf(mac, "lab:%d:task:%d:owner:%d", lab.ID, lab.TaskID, lab.OwnerID)
+	return fmt.Sprintf("%s{%s}", prefix, hex.EncodeToString(mac.Sum(nil))[:32])
+}
+
+// RegisterFlag возвращает флаг лаборатории и регистрирует его в Redactor.
+// Вызывается, когда флаг передаётся в контейнер.
+func (s *SecretService) RegisterFlag(lab *model.Lab) string {
+	flag := s.LabFlag(lab)
+	s.Redactor.Add(flag)
+	return flag
+}
+
+// UnregisterFlag снимает флаг удаляемой лаборатории с учёта в Redactor
+func (s *SecretService) UnregisterFlag(lab *model.Lab) {
+	if flag := s.LabFlag(lab); flag != "" {
+		s.Redactor.Remove(flag)
+	}
+}
+
+// GenerateSecrets создаёт секреты лаборатории по определениям задания и возвращает их значения по именам
+func (s *SecretService) GenerateSecrets(ctx context.Context, labID uint, definitions []model.SecretDefinition) (map[string]string, error) {
+	values := make(map[string]string, len(definitions))
+	records := make([]*model.LabSecret, 0, len(definitions))
+	for _, definition := range definitions {
+		value, err := generateSecret(definition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate secret %s: %w", definition.Name, err)
+		}
+		s.Redactor.Add(value)
+
+		encrypted, err := utils.Encrypt(s.EncryptionKey, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt secret %s: %w", definition.Name, err)
+		}
+		values[definition.Name] = value
+		records = append(records, &model.LabSecret{LabID: labID, Name: definition.Name, Value: encrypted})
+	}
+
+	if err := s.SecretRepository.CreateSecrets(ctx, records); err != nil {
+		return nil, fmt.Errorf("failed to save secrets: %w", err)
+	}
+	return values, nil
+}
+
+// GetSecrets расшифровывает секреты лаборатории и возвращает их значения по именам
+func (s *SecretService) GetSecrets(ctx context.Context, labID uint) (map[string]string, error) {
+	records, err := s.SecretRepository.GetSecrets(ctx, labID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets: %w", err)
+	}
+	values := make(map[string]string, len(records))
+	for _, record := range records {
+		value, err := utils.Decrypt(s.EncryptionKey, record.Value)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "Failed to decrypt lab secret", "lab_id", labID, "secret", record.Name, "error", err)
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", record.Name, err)
+		}
+		s.Redactor.Add(value)
+		values[record.Name] = value
+	}
+	return values, nil
+}
+
+// DeleteSecrets удаляет секреты лаборатории и снимает их с учёта в Redactor
+func (s *SecretService) DeleteSecrets(ctx context.Context, labID uint) error {
+	values, err := s.GetSecrets(ctx, labID)
+	if err != nil {
+		return err
+	}
+	if err := s.SecretRepository.DeleteSecrets(ctx, labID); err != nil {
+		return fmt.Errorf("failed to delete secrets: %w", err)
+	}
+	for _, value := range values {
+		s.Redactor.Remove(value)
+	}
+	return nil
+}
+
+// Генерирует случайное значение секрета заданной длины и формата
+func generateSecret(definition model.SecretDefinition) (string, error) {
+	length := definition.Length
+	if length == 0 {
+		length = defaultSecretLength
+	}
+
+	switch definition.Format {
+	case "", SecretFormatHex:
+		buf := make([]byte, (length+1)/2)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf)[:length], nil
+	case SecretFormatAlnum:
+		out := make([]byte, length)
+		max := big.NewInt(int64(len(alnumAlphabet)))
+		for i := range out {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			out[i] = alnumAlphabet[n.Int64()]
+		}
+		return string(out), nil
+	default:
+		return "", fmt.Errorf("unknown secret format %q", definition.Format)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"regexp"
+)
+
+var ErrInvalidTemplate = errors.New("invalid lab template")
+
+// Имя сервиса используется в имени контейнера и как DNS-имя в сети лаборатории
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type TemplateService struct {
+	TemplateRepository interfaces.LabTemplateInterface
+	Logger             *slog.Logger
+}
+
+func NewTemplateService(templateRepository interfaces.LabTemplateInterface, logger *slog.Logger) *TemplateService {
+	return &TemplateService{
+		TemplateRepository: templateRepository,
+		Logger:             logger,
+	}
+}
+
+func (s *TemplateService) CreateTemplate(ctx context.Context, template *model.LabTemplate) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	if err := s.TemplateRepository.CreateTemplate(ctx, template); err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+	return nil
+}
+
+func (s *TemplateService) UpdateTemplate(ctx context.Context, template *model.LabTemplate) error {
+	existing, err := s.TemplateRepository.GetTemplate(ctx, template.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
+	}
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	template.CreatedAt = existing.CreatedAt
+	if err := s.TemplateRepository.UpdateTemplate(ctx, template); err != nil {
+		return fmt.Errorf("failed to update template %d: %w", template.ID, err)
+	}
+	return nil
+}
+
+func (s *TemplateService) DeleteTemplate(ctx context.Context, id uint) error {
+	if err := s.TemplateRepository.DeleteTemplate(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete template %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *TemplateService) GetTemplate(ctx context.Context, id uint) (*model.LabTemplate, error) {
+	template, err := s.TemplateRepository.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return template, nil
+}
+
+func (s *TemplateService) GetAllTemplates(ctx context.Context) ([]*model.LabTemplate, error) {
+	templates, err := s.TemplateRepository.GetAllTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+	return templates, nil
+}
+
+// Проверяет имена, образы и зависимости сервисов шаблона
+func validateTemplate(template *model.LabTemplate) error {
+	if template.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	if len(template.Services) == 0 {
+		return fmt.Errorf("%w: at least one service is required", ErrInvalidTemplate)
+	}
+	names := make(map[string]bool, len(template.Services))
+	for _, svc := range template.Services {
+		if !serviceNamePattern.MatchString(svc.Name) {
+			return fmt.Errorf("%w: invalid service name %q", ErrInvalidTemplate, svc.Name)
+		}
+		if names[svc.Name] {
+			return fmt.Errorf("%w: duplicate service %q", ErrInvalidTemplate, svc.Name)
+		}
+		names[svc.Name] = true
+		if svc.Image == "" {
+			return fmt.Errorf("%w: service %q has no image", ErrInvalidTemplate, svc.Name)
+		}
+		switch svc.TerminalType {
+		case "", model.TerminalTTYD, model.TerminalWetty:
+		default:
+			return fmt.Errorf("%w: service %q has unknown terminal type %q", ErrInvalidTemplate, svc.Name, svc.TerminalType)
+		}
+		for _, port := range svc.Ports {
+			if port <= 0 || port > 65535 {
+				return fmt.Errorf("%w: service %q has invalid port %d", ErrInvalidTemplate, svc.Name, port)
+			}
+		}
+	}
+	for _, svc := range template.Services {
+		for _, dep := range svc.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("%w: service %q depends on unknown service %q", ErrInvalidTemplate, svc.Name, dep)
+			}
+		}
+	}
+	if _, err := orderServices(template.Services); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Упорядочивает сервисы так, чтобы зависимости шли раньше зависящих от них сервисов
+func orderServices(services []model.TemplateService) ([]model.TemplateService, error) {
+	byName := make(map[string]model.TemplateService, len(services))
+	for _, svc := range services {
+		byName[svc.Name] = svc
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(services))
+	ordered := make([]model.TemplateService, 0, len(services))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%w: dependency cycle through service %q", ErrInvalidTemplate, name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		ordered = append(ordered, byName[name])
+		return nil
+	}
+	for _, svc := range services {
+		if err := visit(svc.Name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/creack/pty"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var ErrRecordingNotFound = errors.New("recording file not found")
+
+// Размер терминала по умолчанию, если клиент его не передал
+const (
+	defaultTerminalCols = 80
+	defaultTerminalRows = 24
+)
+
+// Запускает login-shell: bash, если он есть в образе, иначе sh
+const terminalShell = "command -v bash >/dev/null 2>&1 && exec bash -l || exec sh -l"
+
+// TerminalService проксирует интерактивные терминальные сессии в контейнеры лабораторий
+// и, если это включено в задании, записывает их в формате asciinema v2.
+type TerminalService struct {
+	LabService          *LabService
+	RecordingRepository interfaces.RecordingInterface
+	RecordingsDir       string
+	Logger              *slog.Logger
+}
+
+func NewTerminalService(labService *LabService, recordingRepository interfaces.RecordingInterface, recordingsDir string, logger *slog.Logger) *TerminalService {
+	return &TerminalService{
+		LabService:          labService,
+		RecordingRepository: recordingRepository,
+		RecordingsDir:       recordingsDir,
+		Logger:              logger,
+	}
+}
+
+// TerminalSession — shell внутри контейнера, подключённый к псевдотерминалу
+type TerminalSession struct {
+	service   *TerminalService
+	cmd       *exec.Cmd
+	pty       *os.File
+	cast      *castWriter
+	recording *model.TerminalRecording
+	closeOnce sync.Once
+
+	// Для записи сессии в журнал команд при закрытии
+	labID       uint
+	userID      uint
+	containerID string
+	startedAt   time.Time
+}
+
+// OpenSession запускает shell в контейнере сервиса лаборатории для её владельца или администратора
+func (s *TerminalService) OpenSession(ctx context.Context, labID uint, userID uint, admin bool, serviceName string, cols, rows int) (*TerminalSession, error) {
+	lab, err := s.LabService.GetOwnLab(ctx, labID, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+	containerID, err := s.LabService.ResolveContainer(lab, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if cols <= 0 || rows <= 0 {
+		cols, rows = defaultTerminalCols, defaultTerminalRows
+	}
+
+	// Сессия живёт дольше контекста запроса на создание, поэтому команда не привязана к ctx
+	cmd := exec.Command(s.LabService.Runtime, "exec", "-it", "-e", "TERM=xterm-256color", containerID, "sh", "-c", terminalShell)
+	s.Logger.DebugContext(ctx, "Running command", "cmd", cmd.String())
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start terminal in container %s: %w", containerID, err)
+	}
+	session := &TerminalSession{service: s, cmd: cmd, pty: ptmx,
+		labID: lab.ID, userID: userID, containerID: containerID, startedAt: time.Now()}
+
+	if lab.RecordTerminal {
+		if err := s.startRecording(ctx, session, lab, userID, serviceName, cols, rows); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	s.Logger.InfoContext(ctx, "Terminal session opened",
+		"lab_id", lab.ID, "user_id", userID, "service", serviceName, "recorded", lab.RecordTerminal)
+	return session, nil
+}
+
+// Создаёт файл записи и строку в БД
+func (s *TerminalService) startRecording(ctx context.Context, session *TerminalSession, lab *model.Lab, userID uint, serviceName string, cols, rows int) error {
+	if err := os.MkdirAll(s.RecordingsDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+	startedAt := time.Now()
+	filePath := filepath.Join(s.RecordingsDir,
+		fmt.Sprintf("lab_%d_user_%d_%s.cast", lab.ID, userID, startedAt.Format("20060102_150405.000000000")))
+
+	cast, err := newCastWriter(filePath, castHeader{
+		Width:  cols,
+		Height: rows,
+		Title:  fmt.Sprintf("lab %d %s", lab.ID, serviceName),
+		Env:    map[string]string{"TERM": "xterm-256color", "SHELL": "/bin/sh"},
+	})
+	if err != nil {
+		return err
+	}
+	recording := &model.TerminalRecording{
+		LabID:       lab.ID,
+		TaskID:      lab.TaskID,
+		UserID:      userID,
+		ServiceName: serviceName,
+		FilePath:    filePath,
+		Width:       cols,
+		Height:      rows,
+		StartedAt:   startedAt,
+	}
+	if err := s.RecordingRepository.CreateRecording(ctx, recording); err != nil {
+		cast.Close()
+		os.Remove(filePath)
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	session.cast = cast
+	session.recording = recording
+	return nil
+}
+
+// Read читает вывод терминала и записывает его как событие "o"
+func (t *TerminalSession) Read(p []byte) (int, error) {
+	n, err := t.pty.Read(p)
+	if n > 0 && t.cast != nil {
+		if recErr := t.cast.Event(castOutput, t.redact(p[:n])); recErr != nil {
+			t.service.Logger.Warn("Failed to record terminal output", "error", recErr, "recording_id", t.recording.ID)
+		}
+	}
+	return n, err
+}
+
+// Write передаёт ввод пользователя в терминал и записывает его как событие "i"
+func (t *TerminalSession) Write(p []byte) (int, error) {
+	if t.cast != nil {
+		if err := t.cast.Event(castInput, t.redact(p)); err != nil {
+			t.service.Logger.Warn("Failed to record terminal input", "error", err, "recording_id", t.recording.ID)
+		}
+	}
+	return t.pty.Write(p)
+}
+
+// Вырезает секреты лаборатории из данных перед записью: пользователь видит их в терминале, но не в записи
+func (t *TerminalSession) redact(p []byte) []byte {
+	return []byte(t.service.LabService.SecretService.Redactor.Redact(string(p)))
+}
+
+// Resize меняет размер терминала и записывает событие "r"
+func (t *TerminalSession) Resize(cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	if err := pty.Setsize(t.pty, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}); err != nil {
+		return fmt.Errorf("failed to resize terminal: %w", err)
+	}
+	if t.cast != nil {
+		if err := t.cast.Resize(cols, rows); err != nil {
+			t.service.Logger.Warn("Failed to record terminal resize", "error", err, "recording_id", t.recording.ID)
+		}
+	}
+	return nil
+}
+
+// Close завершает shell и сохраняет длительность и размер записи
+func (t *TerminalSession) Close() error {
+	var closeErr error
+	t.closeOnce.Do(func() {
+		t.pty.Close()
+		if t.cmd.Process != nil {
+			t.cmd.Process.Kill()
+		}
+		t.cmd.Wait()
+		// Сессия попадает в журнал команд как один запуск shell; её ввод и вывод есть в записи, если она включена
+		t.service.LabService.AuditService.RecordExec(context.Background(), t.labID, t.userID, t.containerID,
+			terminalShell, t.cmd.ProcessState.ExitCode(), time.Since(t.startedAt), "")
+		if t.cast == nil {
+			return
+		}
+
+		duration, size, err := t.cast.Close()
+		if err != nil {
+			t.service.Logger.Error("Failed to finish recording", "error", err, "recording_id", t.recording.ID)
+			closeErr = err
+		}
+		endedAt := time.Now()
+		t.recording.EndedAt = &endedAt
+		t.recording.Duration = duration.Seconds()
+		t.recording.SizeBytes = size
+		// Контекст запроса к этому моменту обычно уже отменён
+		if err := t.service.RecordingRepository.UpdateRecording(context.Background(), t.recording); err != nil {
+			closeErr = fmt.Errorf("failed to update recording %d: %w", t.recording.ID, err)
+		}
+	})
+	return closeErr
+}
+
+// GetRecordings возвращает записи терминальных сессий лаборатории её владельцу или администратору
+func (s *TerminalService) GetRecordings(ctx context.Context, labID, userID uint, admin bool) ([]*model.TerminalRecording, error) {
+	if _, err := s.LabService.GetOwnLab(ctx, labID, userID, admin); err != nil {
+		return nil, err
+	}
+	recordings, err := s.RecordingRepository.GetRecordingsByLab(ctx, labID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recordings: %w", err)
+	}
+	return recordings, nil
+}
+
+// OpenRecording возвращает метаданные и открытый .cast файл записи лаборатории labID её владельцу или администратору
+func (s *TerminalService) OpenRecording(ctx context.Context, labID, id, userID uint, admin bool) (*model.TerminalRecording, *os.File, error) {
+	if _, err := s.LabService.GetOwnLab(ctx, labID, userID, admin); err != nil {
+		return nil, nil, err
+	}
+	recording, err := s.RecordingRepository.GetRecording(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if recording.LabID != labID {
+		return nil, nil, fmt.Errorf("%w: recording %d of lab %d", ErrRecordingNotFound, id, labID)
+	}
+	file, err := os.Open(recording.FilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("%w: recording %d", ErrRecordingNotFound, id)
+		}
+		return nil, nil, fmt.Errorf("failed to open recording %d: %w", id, err)
+	}
+	return recording, file, nil
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lab/internal/events"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+	_ "time/tzdata" // Расписания пулов задаются в часовых поясах IANA, а в образе сервиса может не быть zoneinfo
+)
+
+var ErrInvalidWarmPool = errors.New("invalid warm pool")
+
+const maxWarmPoolSize = 100
+
+var poolWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// WarmPoolService поддерживает тёплые пулы заданий: досоздаёт лаборатории, когда их разбирают
+// или начинается время по расписанию, и удаляет лишние, когда время заканчивается или пул уменьшен
+type WarmPoolService struct {
+	LabService *LabService
+	Repository interfaces.WarmPoolInterface
+	Logger     *slog.Logger
+
+	refill chan struct{}
+}
+
+func NewWarmPoolService(labService *LabService, repository interfaces.WarmPoolInterface, logger *slog.Logger) *WarmPoolService {
+	return &WarmPoolService{
+		LabService: labService,
+		Repository: repository,
+		Logger:     logger,
+		refill:     make(chan struct{}, 1),
+	}
+}
+
+// SavePool создаёт или заменяет настройки пула задания и возвращает сохранённый пул
+func (s *WarmPoolService) SavePool(ctx context.Context, pool *model.WarmPool) (*model.WarmPool, error) {
+	if err := validatePool(pool); err != nil {
+		return nil, err
+	}
+	if pool.Size > 0 {
+		if err := s.LabService.CheckPoolable(ctx, pool.TaskID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Repository.SavePool(ctx, pool); err != nil {
+		return nil, err
+	}
+	s.Refill()
+	return s.GetPool(ctx, pool.TaskID)
+}
+
+// GetPool возвращает настройки пула задания вместе с текущим и целевым размером
+func (s *WarmPoolService) GetPool(ctx context.Context, taskID uint) (*model.WarmPool, error) {
+	pool, err := s.Repository.GetPool(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillStatus(ctx, pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// GetAllPools возвращает настройки всех пулов вместе с текущим и целевым размером
+func (s *WarmPoolService) GetAllPools(ctx context.Context) ([]*model.WarmPool, error) {
+	pools, err := s.Repository.GetAllPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ready, err := s.readyLabs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, pool := range pools {
+		pool.Ready = ready[pool.TaskID]
+		pool.Target = poolTarget(pool, now)
+	}
+	return pools, nil
+}
+
+// DeletePool удаляет настройки пула; его лаборатории удаляются при следующем проходе
+func (s *WarmPoolService) DeletePool(ctx context.Context, taskID uint) error {
+	if err := s.Repository.DeletePool(ctx, taskID); err != nil {
+		return err
+	}
+	s.Refill()
+	return nil
+}
+
+// Refill просит фоновый обработчик привести пулы к целевому размеру, не дожидаясь следующего прохода
+func (s *WarmPoolService) Refill() {
+	select {
+	case s.refill <- struct{}{}:
+	default:
+	}
+}
+
+// Run приводит пулы к целевому размеру раз в interval, после изменения настроек и после
+// выдачи лаборатории из пула, пока не отменён ctx
+func (s *WarmPoolService) Run(ctx context.Context, interval time.Duration) {
+	sub := s.LabService.Events.Subscribe(events.Filter{}, 0)
+	defer func() { sub.Close() }()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.Refill()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				sub = s.LabService.Events.Subscribe(events.Filter{}, 0)
+				s.Refill()
+				continue
+			}
+			if event.Type == model.EventLabCreated && event.Data["warm_pool"] == true {
+				s.Refill()
+			}
+		case <-ticker.C:
+			s.reconcile(ctx)
+		case <-s.refill:
+			s.reconcile(ctx)
+		}
+	}
+}
+
+// Досоздаёт и удаляет лаборатории пулов до размера по расписанию. Отмена ctx прерывает
+// проход между лабораториями, но не создание или удаление уже начатой.
+func (s *WarmPoolService) reconcile(ctx context.Context) {
+	pools, err := s.Repository.GetAllPools(ctx)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to load warm pools", "error", err)
+		return
+	}
+	ready, err := s.readyLabs(ctx)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to count warm pool labs", "error", err)
+		return
+	}
+
+	now := time.Now()
+	targets := make(map[uint]int, len(pools))
+	for _, pool := range pools {
+		targets[pool.TaskID] = poolTarget(pool, now)
+	}
+	// Лаборатории пулов, настройки которых удалены
+	for taskID := range ready {
+		if _, ok := targets[taskID]; !ok {
+			targets[taskID] = 0
+		}
+	}
+
+	for _, taskID := range slices.Sorted(maps.Keys(targets)) {
+		if targets[taskID] == ready[taskID] || ctx.Err() != nil {
+			continue
+		}
+		s.reconcilePool(ctx, taskID, targets[taskID])
+	}
+}
+
+// Приводит один пул к размеру target под блокировкой пула. Несколько реплик проходят пулы
+// одновременно, поэтому число готовых лабораторий пересчитывается уже после захвата блокировки.
+func (s *WarmPoolService) reconcilePool(ctx context.Context, taskID uint, target int) {
+	unlock, acquired, err := s.LabService.Locks.LockPool(ctx, taskID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to lock warm pool", "error", err, "task_id", taskID)
+		return
+	}
+	if !acquired {
+		s.Logger.DebugContext(ctx, "Warm pool is reconciled by another pass", "task_id", taskID)
+		return
+	}
+	defer unlock()
+	ready, err := s.readyLabs(ctx)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to count warm pool labs", "error", err, "task_id", taskID)
+		return
+	}
+
+	opCtx := context.WithoutCancel(ctx)
+	current := ready[taskID]
+	if current > target {
+		removed, err := s.LabService.ShrinkPool(opCtx, taskID, current-target)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "Failed to shrink warm pool", "error", err, "task_id", taskID)
+		} else {
+			s.Logger.InfoContext(ctx, "Warm pool shrunk", "task_id", taskID, "removed", removed, "target", target)
+		}
+		return
+	}
+	for ; current < target && ctx.Err() == nil; current++ {
+		if err := s.LabService.FillPool(opCtx, taskID); err != nil {
+			// Следующая попытка — при следующем проходе, чтобы не запускать контейнеры без остановки
+			s.Logger.ErrorContext(ctx, "Failed to fill warm pool", "error", err, "task_id", taskID, "ready", current, "target", target)
+			break
+		}
+	}
+}
+
+// Число готовых лабораторий пула по заданиям
+func (s *WarmPoolService) readyLabs(ctx context.Context) (map[uint]int, error) {
+	counts, err := s.LabService.LabRepository.CountLabs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ready := make(map[uint]int)
+	for _, count := range counts {
+		if count.Status == model.LabStatusPooled {
+			ready[count.TaskID] += int(count.Count)
+		}
+	}
+	return ready, nil
+}
+
+func (s *WarmPoolService) fillStatus(ctx context.Context, pool *model.WarmPool) error {
+	ready, err := s.readyLabs(ctx)
+	if err != nil {
+		return err
+	}
+	pool.Ready = ready[pool.TaskID]
+	pool.Target = poolTarget(pool, time.Now())
+	return nil
+}
+
+// Размер пула в момент now: Size внутри любого интервала расписания, иначе 0
+func poolTarget(pool *model.WarmPool, now time.Time) int {
+	if len(pool.Schedule) == 0 {
+		return pool.Size
+	}
+	location := time.UTC
+	if pool.TimeZone != "" {
+		if loaded, err := time.LoadLocation(pool.TimeZone); err == nil {
+			location = loaded
+		}
+	}
+	now = now.In(location)
+	for _, window := range pool.Schedule {
+		if windowActive(window, now) {
+			return pool.Size
+		}
+	}
+	return 0
+}
+
+// Попадает ли now в интервал. Интервал через полночь относится к дню, в который начался.
+func windowActive(window model.PoolWindow, now time.Time) bool {
+	start, _ := parseClock(window.Start)
+	end, _ := parseClock(window.End)
+	minute := now.Hour()*60 + now.Minute()
+	onDay := func(day time.Weekday) bool {
+		if len(window.Days) == 0 {
+			return true
+		}
+		return slices.ContainsFunc(window.Days, func(name string) bool { return poolWeekdays[name] == day })
+	}
+	yesterday := now.AddDate(0, 0, -1).Weekday()
+	switch {
+	case start < end:
+		return onDay(now.Weekday()) && minute >= start && minute < end
+	case start == end:
+		// Совпадающие начало и конец — весь день
+		return onDay(now.Weekday())
+	default:
+		return onDay(now.Weekday()) && minute >= start || onDay(yesterday) && minute < end
+	}
+}
+
+// Разбирает время ЧЧ:ММ в минуты от полуночи
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+func validatePool(pool *model.WarmPool) error {
+	if pool.TaskID == 0 {
+		return fmt.Errorf("%w: task_id is required", ErrInvalidWarmPool)
+	}
+	if pool.Size < 0 || pool.Size > maxWarmPoolSize {
+		return fmt.Errorf("%w: size must be between 0 and %d", ErrInvalidWarmPool, maxWarmPoolSize)
+	}
+	if pool.TimeZone != "" {
+		if _, err := time.LoadLocation(pool.TimeZone); err != nil {
+			return fmt.Errorf("%w: unknown time_zone %q", ErrInvalidWarmPool, pool.TimeZone)
+		}
+	}
+	for i, window := range pool.Schedule {
+		if _, err := parseClock(window.Start); err != nil {
+			return fmt.Errorf("%w: schedule[%d]: start must be HH:MM", ErrInvalidWarmPool, i)
+		}
+		if _, err := parseClock(window.End); err != nil {
+			return fmt.Errorf("%w: schedule[%d]: end must be HH:MM", ErrInvalidWarmPool, i)
+		}
+		for _, day := range window.Days {
+			if _, ok := poolWeekdays[day]; !ok {
+				return fmt.Errorf("%w: schedule[%d]: unknown day %q", ErrInvalidWarmPool, i, day)
+			}
+		}
+	}
+	return nil
+}
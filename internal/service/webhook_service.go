@@ -0,0 +1,392 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lab/internal/events"
+	"lab/internal/interfaces"
+	"lab/internal/model"
+	"lab/utils"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// Заголовки запроса доставки вебхука
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookService рассылает события лабораторий подписчикам подписанными POST-запросами.
+// Неудачные доставки повторяются с экспоненциальной задержкой, каждая попытка сохраняется.
+type WebhookService struct {
+	WebhookRepository interfaces.WebhookInterface
+	Events            *events.Bus
+	EncryptionKey     string // Ключ шифрования секретов подписок
+	Client            *http.Client
+	MaxAttempts       int
+	BaseBackoff       time.Duration // Задержка перед второй попыткой, дальше удваивается
+	MaxBackoff        time.Duration
+	Logger            *slog.Logger
+
+	mu     sync.Mutex
+	runCtx context.Context
+	wg     sync.WaitGroup
+}
+
+func NewWebhookService(webhookRepository interfaces.WebhookInterface, eventBus *events.Bus, encryptionKey string, client *http.Client, maxAttempts int, baseBackoff, maxBackoff time.Duration, logger *slog.Logger) *WebhookService {
+	return &WebhookService{
+		WebhookRepository: webhookRepository,
+		Events:            eventBus,
+		EncryptionKey:     encryptionKey,
+		Client:            client,
+		MaxAttempts:       maxAttempts,
+		BaseBackoff:       baseBackoff,
+		MaxBackoff:        maxBackoff,
+		Logger:            logger,
+	}
+}
+
+// SignWebhook вычисляет подпись тела запроса: sha256=hex(HMAC-SHA256(secret, "timestamp.body"))
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook проверяет подпись запроса на стороне получателя
+func VerifyWebhook(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
+
+// Run подписывается на шину событий и доставляет события, пока не отменён ctx.
+// Перед этим возобновляются доставки, не завершённые до перезапуска.
+func (s *WebhookService) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.runCtx = ctx
+	s.mu.Unlock()
+
+	// Курсор — ID последнего разосланного события; по нему подписка восстанавливается без пропусков
+	cursor := s.Events.LastID()
+	sub := s.Events.Subscribe(events.Filter{}, cursor)
+	defer func() { sub.Close() }()
+
+	pending, err := s.WebhookRepository.GetPendingDeliveries(ctx)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to load pending webhook deliveries", "error", err)
+	}
+	for _, delivery := range pending {
+		s.startDelivery(delivery)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Отстали от шины: пропущенные события дочитываются из истории шины
+				s.Logger.WarnContext(ctx, "Webhook dispatcher fell behind the event bus, resubscribing", "after", cursor)
+				sub = s.Events.Subscribe(events.Filter{}, cursor)
+				if sub.Truncated {
+					s.Logger.ErrorContext(ctx, "Event history was truncated, some events were not delivered to webhooks", "after", cursor)
+				}
+				continue
+			}
+			cursor = event.ID
+			s.dispatch(ctx, event)
+		}
+	}
+}
+
+// Wait ждёт завершения запущенных доставок
+func (s *WebhookService) Wait() {
+	s.wg.Wait()
+}
+
+// Создаёт доставки события для всех подходящих подписок
+func (s *WebhookService) dispatch(ctx context.Context, event model.LabEvent) {
+	subscriptions, err := s.WebhookRepository.GetActiveSubscriptions(ctx)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to load webhook subscriptions", "error", err, "event_id", event.ID)
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "Failed to encode webhook payload", "error", err, "event_id", event.ID)
+		return
+	}
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+		delivery := &model.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         model.DeliveryPending,
+		}
+		if err := s.WebhookRepository.CreateDelivery(ctx, delivery); err != nil {
+			continue
+		}
+		s.startDelivery(delivery)
+	}
+}
+
+// Запускает доставку в фоне в контексте Run
+func (s *WebhookService) startDelivery(delivery *model.WebhookDelivery) {
+	s.mu.Lock()
+	ctx := s.runCtx
+	s.mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.deliver(ctx, delivery)
+	}()
+}
+
+// Выполняет попытки доставки, пока не получен ответ 2xx или не исчерпаны попытки
+func (s *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	for delivery.Attempts < s.MaxAttempts {
+		if delivery.NextAttemptAt != nil {
+			timer := time.NewTimer(time.Until(*delivery.NextAttemptAt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return // Доставка останется pending и продолжится после перезапуска
+			case <-timer.C:
+			}
+		}
+
+		subscription, err := s.WebhookRepository.GetSubscription(ctx, delivery.SubscriptionID)
+		if err != nil || !subscription.Active {
+			s.Logger.WarnContext(ctx, "Webhook subscription is gone or disabled, dropping delivery", "delivery_id", delivery.ID)
+			s.finishDelivery(ctx, delivery, model.DeliveryFailed)
+			return
+		}
+		secret, err := utils.Decrypt(s.EncryptionKey, subscription.Secret)
+		if err != nil {
+			s.Logger.ErrorContext(ctx, "Failed to decrypt webhook secret", "error", err, "subscription_id", subscription.ID)
+			s.finishDelivery(ctx, delivery, model.DeliveryFailed)
+			return
+		}
+
+		delivery.Attempts++
+		attempt := s.attempt(ctx, subscription.URL, secret, delivery)
+		s.WebhookRepository.CreateAttempt(ctx, attempt)
+		if attempt.Error == "" {
+			s.finishDelivery(ctx, delivery, model.DeliverySucceeded)
+			return
+		}
+		s.Logger.WarnContext(ctx, "Webhook delivery attempt failed",
+			"delivery_id", delivery.ID, "attempt", delivery.Attempts, "status_code", attempt.StatusCode, "error", attempt.Error)
+
+		if delivery.Attempts >= s.MaxAttempts {
+			break
+		}
+		nextAttemptAt := time.Now().Add(s.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &nextAttemptAt
+		s.WebhookRepository.UpdateDelivery(ctx, delivery)
+	}
+	s.finishDelivery(ctx, delivery, model.DeliveryFailed)
+}
+
+// Отправляет один подписанный запрос
+func (s *WebhookService) attempt(ctx context.Context, targetURL, secret string, delivery *model.WebhookDelivery) *model.WebhookAttempt {
+	attempt := &model.WebhookAttempt{DeliveryID: delivery.ID, Attempt: delivery.Attempts}
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lab-service-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+
+	startedAt := time.Now()
+	resp, err := s.Client.Do(req)
+	attempt.DurationMs = time.Since(startedAt).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// Задержка перед попыткой после attempts неудачных: BaseBackoff * 2^(attempts-1), не больше MaxBackoff
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.BaseBackoff
+	for i := 1; i < attempts && delay < s.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.MaxBackoff)
+}
+
+func (s *WebhookService) finishDelivery(ctx context.Context, delivery *model.WebhookDelivery, status string) {
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	s.WebhookRepository.UpdateDelivery(ctx, delivery)
+}
+
+// Redeliver повторно отправляет событие доставки новой доставкой
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID uint) (*model.WebhookDelivery, error) {
+	original, err := s.WebhookRepository.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	if _, err := s.WebhookRepository.GetSubscription(ctx, original.SubscriptionID); err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	delivery := &model.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         model.DeliveryPending,
+		RedeliveryOf:   original.ID,
+	}
+	if err := s.WebhookRepository.CreateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to create delivery: %w", err)
+	}
+	s.startDelivery(delivery)
+	return delivery, nil
+}
+
+func (s *WebhookService) CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	if subscription.Secret == "" {
+		return fmt.Errorf("%w: secret is required", ErrInvalidWebhook)
+	}
+	if err := validateWebhook(subscription); err != nil {
+		return err
+	}
+	encrypted, err := utils.Encrypt(s.EncryptionKey, subscription.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	subscription.Secret = encrypted
+	if err := s.WebhookRepository.CreateSubscription(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	subscription.Secret = ""
+	return nil
+}
+
+// UpdateSubscription обновляет подписку. Пустой секрет оставляет прежний.
+func (s *WebhookService) UpdateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	existing, err := s.WebhookRepository.GetSubscription(ctx, subscription.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	if err := validateWebhook(subscription); err != nil {
+		return err
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = existing.Secret
+	} else {
+		encrypted, err := utils.Encrypt(s.EncryptionKey, subscription.Secret)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+		}
+		subscription.Secret = encrypted
+	}
+	subscription.CreatedAt = existing.CreatedAt
+	if err := s.WebhookRepository.UpdateSubscription(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to update webhook subscription %d: %w", subscription.ID, err)
+	}
+	subscription.Secret = ""
+	return nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uint) error {
+	if err := s.WebhookRepository.DeleteSubscription(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id uint) (*model.WebhookSubscription, error) {
+	subscription, err := s.WebhookRepository.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+func (s *WebhookService) GetAllSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	subscriptions, err := s.WebhookRepository.GetAllSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		subscription.Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func (s *WebhookService) GetDeliveries(ctx context.Context, subscriptionID uint) ([]*model.WebhookDelivery, error) {
+	if _, err := s.WebhookRepository.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	deliveries, err := s.WebhookRepository.GetDeliveries(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookService) GetDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error) {
+	delivery, err := s.WebhookRepository.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// Проверяет адрес и типы событий подписки
+func validateWebhook(subscription *model.WebhookSubscription) error {
+	parsed, err := url.Parse(subscription.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !slices.Contains(model.LabEventTypes, eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"lab/internal/config"
+	"lab/internal/events"
+	"lab/internal/interfaces"
+	"lab/internal/migrations"
+	"lab/internal/model"
+	"lab/internal/repository"
+	"lab/internal/service"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testWebhookSecret = "webhook-secret"
+	testSecretsKey    = "bbbbbbbbbbbbbbbbbbbbbbbb"
+	testBackoff       = 20 * time.Millisecond
+)
+
+// Запрос, полученный тестовым получателем вебхуков
+type receivedWebhook struct {
+	deliveryID  uint
+	eventType   string
+	body        string
+	signatureOK bool
+	at          time.Time
+}
+
+// Получатель вебхуков: проверяет подпись и отвечает статусами из statuses, затем 200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests chan receivedWebhook
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get(service.WebhookTimestampHeader), 10, 64)
+	deliveryID, _ := strconv.ParseUint(r.Header.Get(service.WebhookDeliveryHeader), 10, 64)
+	rcv.requests <- receivedWebhook{
+		deliveryID:  uint(deliveryID),
+		eventType:   r.Header.Get(service.WebhookEventHeader),
+		body:        string(body),
+		signatureOK: service.VerifyWebhook(testWebhookSecret, timestamp, body, r.Header.Get(service.WebhookSignatureHeader)),
+		at:          time.Now(),
+	}
+
+	rcv.mu.Lock()
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	rcv.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+// Хранилище, которое сообщает о загрузке незавершённых доставок: Run делает это после подписки на шину
+type readyWebhookRepository struct {
+	interfaces.WebhookInterface
+	ready chan struct{}
+}
+
+func (r *readyWebhookRepository) GetPendingDeliveries(ctx context.Context) ([]*model.WebhookDelivery, error) {
+	defer close(r.ready)
+	return r.WebhookInterface.GetPendingDeliveries(ctx)
+}
+
+type webhookTest struct {
+	service  *service.WebhookService
+	bus      *events.Bus
+	receiver *webhookReceiver
+}
+
+// Запускает WebhookService на SQLite во временном каталоге с одной подпиской на тестовый получатель
+func startWebhookService(t *testing.T, maxAttempts int, statuses ...int) *webhookTest {
+	t.Helper()
+	ctx := context.Background()
+	db, err := config.InitDB(config.Config{
+		DBDriver: config.DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "lab.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := migrations.NewMigrator(sqlDB, db.Dialector.Name(), slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	receiver := &webhookReceiver{statuses: statuses, requests: make(chan receivedWebhook, 16)}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	repo := &readyWebhookRepository{
+		WebhookInterface: repository.NewWebhookRepository(db, slog.Default()),
+		ready:            make(chan struct{}),
+	}
+	bus := events.NewBus(100)
+	svc := service.NewWebhookService(repo, bus, testSecretsKey, server.Client(),
+		maxAttempts, testBackoff, 4*testBackoff, slog.Default())
+	sub := &model.WebhookSubscription{URL: server.URL, Secret: testWebhookSecret, Active: true}
+	if err := svc.CreateSubscription(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	go svc.Run(runCtx)
+	t.Cleanup(func() {
+		cancel()
+		svc.Wait()
+	})
+	select {
+	case <-repo.ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook service did not start")
+	}
+	return &webhookTest{service: svc, bus: bus, receiver: receiver}
+}
+
+func (wt *webhookTest) next(t *testing.T) receivedWebhook {
+	t.Helper()
+	select {
+	case req := <-wt.receiver.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+		return receivedWebhook{}
+	}
+}
+
+// Ждёт, пока доставка перестанет быть pending
+func (wt *webhookTest) finished(t *testing.T, id uint) *model.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		delivery, err := wt.service.GetDelivery(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if delivery.Status != model.DeliveryPending {
+			return delivery
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery %d is still pending", id)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookRetryAndRedeliver(t *testing.T) {
+	wt := startWebhookService(t, 3, http.StatusInternalServerError, http.StatusBadGateway)
+	event := wt.bus.Publish(model.LabEvent{Type: model.EventLabStarted, LabID: 7})
+
+	var attempts []receivedWebhook
+	for range 3 {
+		attempts = append(attempts, wt.next(t))
+	}
+	for i, req := range attempts {
+		if !req.signatureOK {
+			t.Errorf("attempt %d: invalid signature", i+1)
+		}
+		if req.eventType != model.EventLabStarted {
+			t.Errorf("attempt %d: event header %q, want %q", i+1, req.eventType, model.EventLabStarted)
+		}
+		if req.deliveryID != attempts[0].deliveryID || req.body != attempts[0].body {
+			t.Errorf("attempt %d: retry must resend the same delivery", i+1)
+		}
+	}
+	// Задержка удваивается: BaseBackoff перед второй попыткой, 2*BaseBackoff перед третьей
+	if gap := attempts[1].at.Sub(attempts[0].at); gap < testBackoff {
+		t.Errorf("second attempt after %v, want at least %v", gap, testBackoff)
+	}
+	if gap := attempts[2].at.Sub(attempts[1].at); gap < 2*testBackoff {
+		t.Errorf("third attempt after %v, want at least %v", gap, 2*testBackoff)
+	}
+
+	delivery := wt.finished(t, attempts[0].deliveryID)
+	if delivery.Status != model.DeliverySucceeded || delivery.Attempts != 3 || delivery.EventID != event.ID {
+		t.Fatalf("delivery: status %q, attempts %d, event %d", delivery.Status, delivery.Attempts, delivery.EventID)
+	}
+	var codes []int
+	for _, attempt := range delivery.AttemptLog {
+		codes = append(codes, attempt.StatusCode)
+	}
+	if len(codes) != 3 || codes[0] != http.StatusInternalServerError || codes[1] != http.StatusBadGateway || codes[2] != http.StatusOK {
+		t.Errorf("attempt log status codes %v", codes)
+	}
+
+	redelivery, err := wt.service.Redeliver(context.Background(), delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := wt.next(t)
+	if req.deliveryID != redelivery.ID || req.body != attempts[0].body || !req.signatureOK {
+		t.Errorf("redelivery request: delivery %d (want %d), signature ok %v", req.deliveryID, redelivery.ID, req.signatureOK)
+	}
+	if redelivered := wt.finished(t, redelivery.ID); redelivered.Status != model.DeliverySucceeded || redelivered.RedeliveryOf != delivery.ID {
+		t.Errorf("redelivery: status %q, redelivery of %d", redelivered.Status, redelivered.RedeliveryOf)
+	}
+}
+
+func TestWebhookGivesUpAfterMaxAttempts(t *testing.T) {
+	wt := startWebhookService(t, 2, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	wt.bus.Publish(model.LabEvent{Type: model.EventLabStopped, LabID: 7})
+
+	first := wt.next(t)
+	wt.next(t)
+	delivery := wt.finished(t, first.deliveryID)
+	if delivery.Status != model.DeliveryFailed || delivery.Attempts != 2 {
+		t.Errorf("delivery: status %q, attempts %d", delivery.Status, delivery.Attempts)
+	}
+	select {
+	case req := <-wt.receiver.requests:
+		t.Errorf("unexpected attempt after giving up: delivery %d", req.deliveryID)
+	case <-time.After(4 * testBackoff):
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := service.SignWebhook(testWebhookSecret, 100, body)
+	if !service.VerifyWebhook(testWebhookSecret, 100, body, signature) {
+		t.Error("valid signature rejected")
+	}
+	if service.VerifyWebhook(testWebhookSecret, 101, body, signature) {
+		t.Error("signature accepted with another timestamp")
+	}
+	if service.VerifyWebhook(testWebhookSecret, 100, []byte(`{"id":2}`), signature) {
+		t.Error("signature accepted for another body")
+	}
+	if service.VerifyWebhook("other-secret", 100, body, signature) {
+		t.Error("signature accepted with another secret")
+	}
+}